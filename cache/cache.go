@@ -1,74 +1,286 @@
 package cache
 
 import (
+	"container/heap"
+	"container/list"
 	"context"
+	"github.com/anaregdesign/papaya/clock"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type volatile[T any] struct {
 	value      T
 	expiration time.Time
+	element    *list.Element
+	windowed   bool
+	deadline   *deadline
+	cost       int64
+	written    time.Time
+	ttl        time.Duration
+	tags       []string
 }
 
 func (v *volatile[T]) IsExpired() bool {
-	return v.expiration.Before(time.Now())
+	return v.expired(time.Now())
+}
+
+func (v *volatile[T]) expired(now time.Time) bool {
+	return v.expiration.Before(now)
 }
 
 type Cache[S comparable, T any] struct {
 	defaultTTL time.Duration
 	cache      map[S]volatile[T]
 	mu         sync.RWMutex
+	maxEntries int
+	sliding    bool
+	clock      clock.Clock
+	jitter     Jitter
+	maxCost    int64
+	cost       func(T) int64
+	totalCost  int64
+
+	// lru orders keys from most to least recently used.
+	// It is only maintained when the cache is bounded.
+	lru *list.List
+
+	// window orders the keys newest to the cache, which the admission policy
+	// only weighs against those in lru once more than windowSize of them arrive.
+	window     *list.List
+	windowSize int
+
+	// sketch estimates access frequencies for the admission policy,
+	// from keys hashed by hasher.
+	sketch *sketch
+	hasher func(S) uint64
+
+	// deadlines indexes entries by expiration for Flush.
+	deadlines deadlines
+
+	// tagged indexes the keys of the entries carrying each tag.
+	tagged map[string]map[S]struct{}
+
+	listeners []RemovalListener[S, T]
+	spill     spillListener[S, T]
+	pending   []removal[S, T]
+
+	// subscribers receive the changes to the cache through Events.
+	subscribers []*subscriber[S, T]
+
+	// replication is set while the cache is tied to a topic by Replicate.
+	replication   atomic.Pointer[replication[S]]
+	invalidations []S
+
+	stats stats
+
+	// once, done and stopped stop the janitor started by WithJanitor.
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
 }
 
-func NewCache[S comparable, T any](defaultTTL time.Duration) *Cache[S, T] {
-	return &Cache[S, T]{
+func NewCache[S comparable, T any](defaultTTL time.Duration, opts ...Option[S, T]) *Cache[S, T] {
+	o := newOptions(opts)
+	c := &Cache[S, T]{
 		defaultTTL: defaultTTL,
 		cache:      make(map[S]volatile[T]),
+		maxEntries: o.maxEntries,
+		listeners:  o.listeners,
+		spill:      o.spill,
+		sliding:    o.sliding,
+		clock:      o.clock,
+		jitter:     o.jitter,
+		hasher:     o.hasher,
 	}
+	if o.maxCost > 0 && o.cost != nil {
+		c.maxCost = o.maxCost
+		c.cost = o.cost
+	}
+	if c.maxEntries > 0 || c.maxCost > 0 {
+		c.lru = list.New()
+		if o.admission {
+			c.window = list.New()
+			c.windowSize = c.maxEntries / 100
+			if c.windowSize < 1 {
+				c.windowSize = 1
+			}
+			c.sketch = newSketch(c.maxEntries)
+		}
+	}
+	if o.janitor > 0 {
+		c.done = make(chan struct{})
+		c.stopped = make(chan struct{})
+		go c.janitor(o.janitor)
+	}
+	return c
 }
 
 func (c *Cache[S, T]) Get(key S) (T, bool) {
+	if c.lru != nil || c.sliding {
+		return c.getAndTouch(key)
+	}
+
 	c.mu.RLock()
-	defer c.mu.RUnlock()
+	v, ok := c.cache[key]
+	c.mu.RUnlock()
 
-	if v, ok := c.cache[key]; ok {
-		if v.IsExpired() {
-			go c.Delete(key)
+	if ok {
+		if v.expired(c.now()) {
+			c.expire(key)
+			c.stats.lookup(false)
 			var noop T
 			return noop, false
 		}
+		c.stats.lookup(true)
 		return v.value, true
 	}
+	c.stats.lookup(false)
 	var noop T
 	return noop, false
 }
 
-func (c *Cache[S, T]) PutWithExpiration(key S, value T, expiration time.Time) {
+// expire removes key if it is still expired once the exclusive lock is held.
+func (c *Cache[S, T]) expire(key S) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
 
-	c.cache[key] = volatile[T]{
-		value:      value,
-		expiration: expiration,
+	if v, ok := c.cache[key]; ok && v.expired(c.now()) {
+		c.remove(key, Expired)
+	}
+}
+
+// getAndTouch is Get for bounded or sliding caches, where a hit also marks
+// the entry as most recently used or extends its expiration, and therefore
+// needs the exclusive lock.
+func (c *Cache[S, T]) getAndTouch(key S) (T, bool) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.access(key)
+	v, ok := c.live(key)
+	c.stats.lookup(ok)
+	if !ok {
+		var noop T
+		return noop, false
+	}
+	c.touch(key, v)
+	return v.value, true
+}
+
+// live returns the entry for key, removing it instead if it has expired.
+// The caller must hold c.mu exclusively.
+func (c *Cache[S, T]) live(key S) (volatile[T], bool) {
+	v, ok := c.cache[key]
+	if !ok {
+		return v, false
+	}
+	if v.expired(c.now()) {
+		c.remove(key, Expired)
+		return volatile[T]{}, false
+	}
+	return v, true
+}
+
+// touch marks the entry v for key as read: it becomes the most recently used
+// and, with sliding expiration, lives for another ttl. The caller must hold c.mu.
+func (c *Cache[S, T]) touch(key S, v volatile[T]) {
+	if v.element != nil {
+		c.list(v).MoveToFront(v.element)
 	}
+	if c.sliding && v.ttl > 0 {
+		v.expiration = c.now().Add(v.ttl)
+		if v.deadline != nil {
+			v.deadline.expiration = v.expiration
+			heap.Fix(&c.deadlines, v.deadline.index)
+		}
+		c.cache[key] = v
+	}
+}
+
+func (c *Cache[S, T]) PutWithExpiration(key S, value T, expiration time.Time) {
+	c.put(key, value, expiration, expiration.Sub(c.now()), nil)
 }
 
 func (c *Cache[S, T]) PutWithTTL(key S, value T, ttl time.Duration) {
-	c.PutWithExpiration(key, value, time.Now().Add(ttl))
+	ttl = c.jitter.Apply(ttl)
+	c.put(key, value, c.now().Add(ttl), ttl, nil)
 }
 
 func (c *Cache[S, T]) Put(key S, value T) {
 	c.PutWithTTL(key, value, c.defaultTTL)
 }
 
-func (c *Cache[S, T]) Delete(key S) {
+// put stores value under key until expiration, replacing the tags of the key.
+// The ttl is what a sliding expiration extends the entry by on every hit.
+func (c *Cache[S, T]) put(key S, value T, expiration time.Time, ttl time.Duration, tags []string) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
+
+	c.set(key, value, expiration, ttl, tags)
+}
 
-	if _, ok := c.cache[key]; ok {
-		delete(c.cache, key)
+// set is put for a caller that holds c.mu.
+func (c *Cache[S, T]) set(key S, value T, expiration time.Time, ttl time.Duration, tags []string) {
+	var cost int64
+	if c.cost != nil {
+		if cost = c.cost(value); cost > c.maxCost {
+			c.remove(key, Evicted)
+			c.invalidate(key)
+			return
+		}
+	}
+	v := volatile[T]{
+		value:      value,
+		expiration: expiration,
+		written:    c.now(),
+		ttl:        ttl,
+		tags:       tags,
+	}
+	old, replaced := c.cache[key]
+	if replaced {
+		c.totalCost -= old.cost
+		c.untag(key, old.tags)
+		c.record(key, old, Replaced)
+	}
+	c.tag(key, tags)
+	c.invalidate(key)
+	v.cost = cost
+	c.totalCost += cost
+	if replaced && old.deadline != nil {
+		v.deadline = old.deadline
+		v.deadline.expiration = expiration
+		heap.Fix(&c.deadlines, v.deadline.index)
+	} else {
+		v.deadline = &deadline{key: key, expiration: expiration}
+		heap.Push(&c.deadlines, v.deadline)
+	}
+	if c.lru != nil {
+		if replaced && old.element != nil {
+			v.element, v.windowed = old.element, old.windowed
+			c.list(v).MoveToFront(v.element)
+		} else if c.window != nil {
+			v.element, v.windowed = c.window.PushFront(key), true
+		} else {
+			v.element = c.lru.PushFront(key)
+		}
 	}
+	c.cache[key] = v
+	c.emit(Event[S, T]{Type: EventPut, Key: key, Value: value})
+	if !replaced {
+		c.access(key)
+		c.admit()
+	}
+	c.evict()
+}
+
+func (c *Cache[S, T]) Delete(key S) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.remove(key, Deleted)
+	c.invalidate(key)
 }
 
 func (c *Cache[S, T]) Has(key S) bool {
@@ -78,9 +290,44 @@ func (c *Cache[S, T]) Has(key S) bool {
 }
 func (c *Cache[S, T]) Clear() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
 
+	for k, v := range c.cache {
+		c.record(k, v, Cleared)
+	}
 	c.cache = make(map[S]volatile[T])
+	c.totalCost = 0
+	c.deadlines = nil
+	c.tagged = nil
+	if c.lru != nil {
+		c.lru.Init()
+	}
+	if c.window != nil {
+		c.window.Init()
+	}
+	c.emit(Event[S, T]{Type: EventClear})
+}
+
+// now returns the current time by the clock of the cache.
+func (c *Cache[S, T]) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+// writtenAt returns when the entry for key was last put.
+func (c *Cache[S, T]) writtenAt(key S) (time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	v, ok := c.cache[key]
+	return v.written, ok
+}
+
+// Stats returns a snapshot of the hit, miss and removal counters of the cache.
+func (c *Cache[S, T]) Stats() Stats {
+	return c.stats.snapshot()
 }
 
 func (c *Cache[S, T]) Count() int {
@@ -90,13 +337,200 @@ func (c *Cache[S, T]) Count() int {
 	return len(c.cache)
 }
 
+// Flush removes expired entries. Its cost is proportional to the number of
+// entries that expired, not to the size of the cache.
 func (c *Cache[S, T]) Flush() {
+	c.mu.Lock()
+	defer c.unlock()
+
+	now := c.now()
+	for len(c.deadlines) > 0 && c.deadlines[0].expiration.Before(now) {
+		d := c.deadlines[0]
+		if v, ok := c.cache[d.key.(S)]; !ok || v.deadline != d {
+			heap.Pop(&c.deadlines)
+			continue
+		}
+		c.remove(d.key.(S), Flushed)
+	}
+}
+
+// evict removes least recently used entries until the cache fits its bounds,
+// those of the admission window last. The caller must hold c.mu.
+func (c *Cache[S, T]) evict() {
+	for c.overflows() {
+		switch {
+		case c.lru != nil && c.lru.Len() > 0:
+			c.remove(c.lru.Back().Value.(S), Evicted)
+		case c.window != nil && c.window.Len() > 0:
+			c.remove(c.window.Back().Value.(S), Evicted)
+		default:
+			return
+		}
+	}
+}
+
+// list returns the list holding the element of the entry v.
+func (c *Cache[S, T]) list(v volatile[T]) *list.List {
+	if v.windowed {
+		return c.window
+	}
+	return c.lru
+}
+
+// hash hashes key with the hasher of the cache, if any.
+func (c *Cache[S, T]) hash(key S) uint64 {
+	if c.hasher != nil {
+		return c.hasher(key)
+	}
+	return hash(key)
+}
+
+// access records a read or write of key for the admission policy.
+// The caller must hold c.mu exclusively.
+func (c *Cache[S, T]) access(key S) {
+	if c.sketch != nil {
+		c.sketch.increment(c.hash(key))
+	}
+}
+
+// admit moves the keys overflowing the admission window to the lru list. While
+// the cache overflows, each such candidate evicts the least recently used
+// entries there that make room for it, provided it is accessed more often than
+// every one of them; a candidate that is not is evicted itself, and they stay.
+// The caller must hold c.mu.
+func (c *Cache[S, T]) admit() {
+	for c.window != nil && c.window.Len() > c.windowSize {
+		candidate := c.window.Back().Value.(S)
+		v := c.cache[candidate]
+		c.window.Remove(v.element)
+		v.element, v.windowed = c.lru.PushFront(candidate), false
+		c.cache[candidate] = v
+
+		if victims, ok := c.victims(candidate); ok {
+			for _, victim := range victims {
+				c.remove(victim, Evicted)
+			}
+		} else {
+			c.remove(candidate, Evicted)
+		}
+	}
+}
+
+// victims returns the least recently used entries whose eviction would make the
+// cache fit its bounds, and whether candidate is accessed more often than each
+// of them. The caller must hold c.mu.
+func (c *Cache[S, T]) victims(candidate S) ([]S, bool) {
+	frequency := c.sketch.estimate(c.hash(candidate))
+	entries, cost := len(c.cache), c.totalCost
+	var victims []S
+	for e := c.lru.Back(); c.exceeds(entries, cost); e = e.Prev() {
+		victim := e.Value.(S)
+		if victim == candidate || frequency <= c.sketch.estimate(c.hash(victim)) {
+			return nil, false
+		}
+		victims = append(victims, victim)
+		entries, cost = entries-1, cost-c.cache[victim].cost
+	}
+	return victims, true
+}
+
+func (c *Cache[S, T]) overflows() bool {
+	return c.exceeds(len(c.cache), c.totalCost)
+}
+
+// exceeds reports whether the given number of entries and total cost overflow
+// the bounds of the cache.
+func (c *Cache[S, T]) exceeds(entries int, cost int64) bool {
+	if c.maxEntries > 0 && entries > c.maxEntries {
+		return true
+	}
+	return c.maxCost > 0 && cost > c.maxCost
+}
+
+// remove deletes key and its bookkeeping. The caller must hold c.mu.
+func (c *Cache[S, T]) remove(key S, reason RemovalReason) {
+	v, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	if v.element != nil {
+		c.list(v).Remove(v.element)
+	}
+	if v.deadline != nil && v.deadline.index >= 0 {
+		heap.Remove(&c.deadlines, v.deadline.index)
+	}
+	c.totalCost -= v.cost
+	c.untag(key, v.tags)
+	delete(c.cache, key)
+	c.stats.removal(reason)
+	c.record(key, v, reason)
+	if t, ok := eventType(reason); ok {
+		c.emit(Event[S, T]{Type: t, Key: key, Value: v.value})
+	}
+}
+
+// record queues the removal of the entry v for the listeners. The caller must hold c.mu.
+func (c *Cache[S, T]) record(key S, v volatile[T], reason RemovalReason) {
+	if len(c.listeners) == 0 && c.spill == nil {
+		return
+	}
+	c.pending = append(c.pending, removal[S, T]{key: key, value: v.value, expiration: v.expiration, reason: reason})
+}
+
+// unlock releases c.mu and then delivers the removals and invalidations recorded
+// while it was held, so that listeners are free to call back into the cache.
+func (c *Cache[S, T]) unlock() {
+	pending, listeners := c.pending, c.listeners
+	invalidations := c.invalidations
+	c.pending = nil
+	c.invalidations = nil
+	c.mu.Unlock()
+
+	c.publish(invalidations)
+
+	for _, r := range pending {
+		if c.spill != nil {
+			c.spill(r.key, r.value, r.expiration, r.reason)
+		}
+		for _, listener := range listeners {
+			listener(r.key, r.value, r.reason)
+		}
+	}
+}
+
+// OnRemoval registers listener to be called whenever an entry leaves the cache.
+func (c *Cache[S, T]) OnRemoval(listener RemovalListener[S, T]) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	for k, v := range c.cache {
-		if v.IsExpired() {
-			delete(c.cache, k)
+	c.listeners = append(c.listeners, listener)
+}
+
+// Close stops the janitor started by WithJanitor and waits for it to return.
+// It is safe to call more than once, and on a cache without a janitor.
+func (c *Cache[S, T]) Close() error {
+	c.once.Do(func() {
+		if c.done != nil {
+			close(c.done)
+		}
+	})
+	if c.stopped != nil {
+		<-c.stopped
+	}
+	return nil
+}
+
+func (c *Cache[S, T]) janitor(interval time.Duration) {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.Flush()
+		case <-c.done:
+			return
 		}
 	}
 }
@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"fmt"
+	"github.com/anaregdesign/papaya/clock"
 	"reflect"
 	"testing"
 	"time"
@@ -267,3 +269,586 @@ func TestCache_Has(t *testing.T) {
 		})
 	}
 }
+
+func TestCache_MaxEntries(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name    string
+		c       *Cache[S, T]
+		puts    []S
+		gets    []S
+		then    []S
+		want    []S
+		evicted []S
+	}
+	tests := []testCase[string, int]{
+		{
+			name:    "evict least recently put",
+			c:       NewCache[string, int](time.Minute, WithMaxEntries[string, int](2)),
+			puts:    []string{"a", "b", "c"},
+			want:    []string{"b", "c"},
+			evicted: []string{"a"},
+		},
+		{
+			name:    "get refreshes recency",
+			c:       NewCache[string, int](time.Minute, WithMaxEntries[string, int](2)),
+			puts:    []string{"a", "b"},
+			gets:    []string{"a"},
+			then:    []string{"c"},
+			want:    []string{"a", "c"},
+			evicted: []string{"b"},
+		},
+		{
+			name: "overwrite does not grow",
+			c:    NewCache[string, int](time.Minute, WithMaxEntries[string, int](2)),
+			puts: []string{"a", "b", "a", "b"},
+			want: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, key := range tt.puts {
+				tt.c.Put(key, i)
+			}
+			for _, key := range tt.gets {
+				tt.c.Get(key)
+			}
+			for i, key := range tt.then {
+				tt.c.Put(key, i)
+			}
+			if got := tt.c.Count(); got != len(tt.want) {
+				t.Errorf("Count() = %v, want %v", got, len(tt.want))
+			}
+			for _, key := range tt.want {
+				if !tt.c.Has(key) {
+					t.Errorf("Has(%v) = false, want true", key)
+				}
+			}
+			for _, key := range tt.evicted {
+				if tt.c.Has(key) {
+					t.Errorf("Has(%v) = true, want false", key)
+				}
+			}
+		})
+	}
+}
+
+func TestCache_MaxCost(t *testing.T) {
+	length := func(s string) int64 { return int64(len(s)) }
+	type kv struct {
+		key   string
+		value string
+	}
+	type testCase[S comparable, T any] struct {
+		name    string
+		c       *Cache[S, T]
+		puts    []kv
+		want    []S
+		evicted []S
+	}
+	tests := []testCase[string, string]{
+		{
+			name:    "evict until budget fits",
+			c:       NewCache[string, string](time.Minute, WithMaxCost[string, string](10, length)),
+			puts:    []kv{{"a", "xxxx"}, {"b", "xxxx"}, {"c", "xxxxxx"}},
+			want:    []string{"b", "c"},
+			evicted: []string{"a"},
+		},
+		{
+			name:    "replacement updates cost",
+			c:       NewCache[string, string](time.Minute, WithMaxCost[string, string](10, length)),
+			puts:    []kv{{"a", "xxxxxxxx"}, {"a", "x"}, {"b", "xxxxxxxx"}},
+			want:    []string{"a", "b"},
+			evicted: []string{},
+		},
+		{
+			name:    "oversized value is not retained",
+			c:       NewCache[string, string](time.Minute, WithMaxCost[string, string](10, length)),
+			puts:    []kv{{"a", "x"}, {"b", "xxxx"}, {"c", "xxxxxxxxxxxx"}},
+			want:    []string{"a", "b"},
+			evicted: []string{"c"},
+		},
+		{
+			name:    "oversized replacement drops the old value",
+			c:       NewCache[string, string](time.Minute, WithMaxCost[string, string](10, length)),
+			puts:    []kv{{"a", "x"}, {"b", "xxxx"}, {"b", "xxxxxxxxxxxx"}},
+			want:    []string{"a"},
+			evicted: []string{"b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, p := range tt.puts {
+				tt.c.Put(p.key, p.value)
+			}
+			for _, key := range tt.want {
+				if !tt.c.Has(key) {
+					t.Errorf("Has(%v) = false, want true", key)
+				}
+			}
+			for _, key := range tt.evicted {
+				if tt.c.Has(key) {
+					t.Errorf("Has(%v) = true, want false", key)
+				}
+			}
+			if tt.c.totalCost > 10 {
+				t.Errorf("totalCost = %v, want <= %v", tt.c.totalCost, 10)
+			}
+		})
+	}
+}
+
+func TestCache_OnRemoval(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name string
+		opts []Option[S, T]
+		run  func(c *Cache[S, T])
+		want []RemovalReason
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "delete",
+			run: func(c *Cache[string, int]) {
+				c.Put("a", 1)
+				c.Delete("a")
+				c.Delete("a")
+			},
+			want: []RemovalReason{Deleted},
+		},
+		{
+			name: "replace",
+			run: func(c *Cache[string, int]) {
+				c.Put("a", 1)
+				c.Put("a", 2)
+			},
+			want: []RemovalReason{Replaced},
+		},
+		{
+			name: "clear",
+			run: func(c *Cache[string, int]) {
+				c.Put("a", 1)
+				c.Put("b", 2)
+				c.Clear()
+			},
+			want: []RemovalReason{Cleared, Cleared},
+		},
+		{
+			name: "expire lazily",
+			run: func(c *Cache[string, int]) {
+				c.PutWithTTL("a", 1, -time.Second)
+				c.Get("a")
+			},
+			want: []RemovalReason{Expired},
+		},
+		{
+			name: "flush",
+			run: func(c *Cache[string, int]) {
+				c.PutWithTTL("a", 1, -time.Second)
+				c.Put("b", 2)
+				c.Flush()
+			},
+			want: []RemovalReason{Flushed},
+		},
+		{
+			name: "evict",
+			opts: []Option[string, int]{WithMaxEntries[string, int](1)},
+			run: func(c *Cache[string, int]) {
+				c.Put("a", 1)
+				c.Put("b", 2)
+			},
+			want: []RemovalReason{Evicted},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []RemovalReason
+			c := NewCache[string, int](time.Minute, tt.opts...)
+			c.OnRemoval(func(key string, value int, reason RemovalReason) {
+				got = append(got, reason)
+				// listeners run outside the lock and may call back into the cache
+				c.Count()
+			})
+			tt.run(c)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OnRemoval() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_Stats(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name string
+		opts []Option[S, T]
+		run  func(c *Cache[S, T])
+		want Stats
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "hits and misses",
+			run: func(c *Cache[string, int]) {
+				c.Put("a", 1)
+				c.Get("a")
+				c.Get("a")
+				c.Get("b")
+			},
+			want: Stats{Hits: 2, Misses: 1},
+		},
+		{
+			name: "expirations and flushes",
+			run: func(c *Cache[string, int]) {
+				c.PutWithTTL("a", 1, -time.Second)
+				c.PutWithTTL("b", 1, -time.Second)
+				c.Get("a")
+				c.Flush()
+			},
+			want: Stats{Misses: 1, Expirations: 1, Flushes: 1},
+		},
+		{
+			name: "evictions",
+			opts: []Option[string, int]{WithMaxEntries[string, int](1)},
+			run: func(c *Cache[string, int]) {
+				c.Put("a", 1)
+				c.Put("b", 1)
+				c.Put("c", 1)
+			},
+			want: Stats{Evictions: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string, int](time.Minute, tt.opts...)
+			tt.run(c)
+			if got := c.Stats(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_Flush_deadlines(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name    string
+		ttls    map[S]time.Duration
+		deletes []S
+		want    []S
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "only expired entries are removed",
+			ttls: map[string]time.Duration{"a": -time.Second, "b": time.Minute, "c": -time.Minute, "d": time.Hour},
+			want: []string{"b", "d"},
+		},
+		{
+			name:    "deleted entries leave the index",
+			ttls:    map[string]time.Duration{"a": -time.Second, "b": time.Minute},
+			deletes: []string{"a", "b"},
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string, int](time.Minute)
+			for key, ttl := range tt.ttls {
+				c.PutWithTTL(key, 0, time.Hour)
+				c.PutWithTTL(key, 0, ttl)
+			}
+			for _, key := range tt.deletes {
+				c.Delete(key)
+			}
+			c.Flush()
+			if got := c.Count(); got != len(tt.want) {
+				t.Errorf("Count() = %v, want %v", got, len(tt.want))
+			}
+			if got := len(c.deadlines); got != len(tt.want) {
+				t.Errorf("len(deadlines) = %v, want %v", got, len(tt.want))
+			}
+			for _, key := range tt.want {
+				if !c.Has(key) {
+					t.Errorf("Has(%v) = false, want true", key)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCache_Flush(b *testing.B) {
+	c := NewCache[int, int](time.Hour)
+	for i := 0; i < 1<<20; i++ {
+		c.Put(i, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Flush()
+	}
+}
+
+func TestCache_SlidingExpiration(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name  string
+		opts  []Option[S, T]
+		reads int
+		want  bool
+	}
+	tests := []testCase[string, int]{
+		{
+			name:  "reads keep the entry alive",
+			opts:  []Option[string, int]{WithSlidingExpiration[string, int]()},
+			reads: 6,
+			want:  true,
+		},
+		{
+			name:  "reads do not extend a fixed expiration",
+			reads: 6,
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewManual(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+			c := NewCache[string, int](time.Minute, append(tt.opts, WithClock[string, int](clk))...)
+			c.Put("a", 1)
+			for i := 0; i < tt.reads; i++ {
+				clk.Add(40 * time.Second)
+				c.Get("a")
+			}
+			c.Flush()
+			if got := c.Has("a"); got != tt.want {
+				t.Errorf("Has() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_Admission(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name    string
+		opts    []Option[S, T]
+		hot     []S
+		reads   int
+		scan    []S
+		want    []S
+		evicted []S
+	}
+	scan := make([]string, 100)
+	for i := range scan {
+		scan[i] = fmt.Sprintf("scan-%d", i)
+	}
+	tests := []testCase[string, int]{
+		{
+			name:    "scan flushes the hot set without admission",
+			opts:    []Option[string, int]{WithMaxEntries[string, int](4)},
+			hot:     []string{"a", "b", "c"},
+			reads:   5,
+			scan:    scan,
+			evicted: []string{"a", "b", "c"},
+		},
+		{
+			name:  "scan is rejected with admission",
+			opts:  []Option[string, int]{WithMaxEntries[string, int](4), WithAdmission[string, int]()},
+			hot:   []string{"a", "b", "c"},
+			reads: 5,
+			scan:  scan,
+			want:  []string{"a", "b", "c", "scan-99"},
+		},
+		{
+			name:    "frequent key is admitted",
+			opts:    []Option[string, int]{WithMaxEntries[string, int](4), WithAdmission[string, int]()},
+			hot:     []string{"a", "b", "c"},
+			reads:   1,
+			scan:    []string{"d", "d", "d", "d", "e"},
+			want:    []string{"d", "e"},
+			evicted: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string, int](time.Minute, tt.opts...)
+			for i, key := range tt.hot {
+				c.Put(key, i)
+			}
+			for i := 0; i < tt.reads; i++ {
+				for _, key := range tt.hot {
+					c.Get(key)
+				}
+			}
+			for i, key := range tt.scan {
+				c.Get(key)
+				c.Put(key, i)
+			}
+			if got := c.Count(); got != 4 {
+				t.Errorf("Count() = %v, want %v", got, 4)
+			}
+			for _, key := range tt.want {
+				if !c.Has(key) {
+					t.Errorf("Has(%v) = false, want true", key)
+				}
+			}
+			for _, key := range tt.evicted {
+				if c.Has(key) {
+					t.Errorf("Has(%v) = true, want false", key)
+				}
+			}
+		})
+	}
+}
+
+func TestCache_Admission_window(t *testing.T) {
+	c := NewCache[string, int](time.Minute, WithMaxEntries[string, int](3), WithAdmission[string, int]())
+	for i, key := range []string{"a", "b", "c"} {
+		c.Put(key, i)
+		for j := 0; j < 5; j++ {
+			c.Get(key)
+		}
+	}
+
+	c.Put("d", 3)
+	if _, ok := c.Get("d"); !ok {
+		t.Errorf("Get(%v) after Put() = false, want true", "d")
+	}
+	if got := c.Count(); got != 3 {
+		t.Errorf("Count() = %v, want %v", got, 3)
+	}
+}
+
+func TestCache_Admission_cost(t *testing.T) {
+	length := func(s string) int64 { return int64(len(s)) }
+	c := NewCache[string, string](time.Minute, WithMaxCost[string, string](10, length), WithAdmission[string, string]())
+	c.Put("y", "y")
+	c.Put("x", "xxx")
+	c.Put("n1", "n")
+	for i := 0; i < 6; i++ {
+		c.Get("x")
+	}
+	for i := 0; i < 3; i++ {
+		c.Get("c")
+	}
+	c.Put("c", "cccccc")
+
+	// c leaves the window and would need both y and x evicted to fit. It is
+	// accessed more often than y but less than x, so neither is evicted for it.
+	c.Put("n2", "nn")
+	for _, key := range []string{"x", "y", "n2"} {
+		if !c.Has(key) {
+			t.Errorf("Has(%v) = false, want true", key)
+		}
+	}
+	if c.Has("c") {
+		t.Errorf("Has(%v) = true, want false", "c")
+	}
+}
+
+func TestCache_WithClock(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name    string
+		opts    []Option[S, T]
+		advance []time.Duration
+		flush   bool
+		want    bool
+		wantTTL time.Duration
+	}
+	tests := []testCase[string, int]{
+		{
+			name:    "live until the ttl passes",
+			advance: []time.Duration{time.Minute - time.Second},
+			want:    true,
+			wantTTL: time.Second,
+		},
+		{
+			name:    "expired once the ttl passes",
+			advance: []time.Duration{time.Minute + time.Second},
+		},
+		{
+			name:    "flushed once the ttl passes",
+			advance: []time.Duration{time.Minute + time.Second},
+			flush:   true,
+		},
+		{
+			name:    "sliding expiration extends on every hit",
+			opts:    []Option[string, int]{WithSlidingExpiration[string, int]()},
+			advance: []time.Duration{50 * time.Second, 50 * time.Second, 50 * time.Second},
+			want:    true,
+			wantTTL: 10 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewManual(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+			c := NewCache[string, int](time.Minute, append(tt.opts, WithClock[string, int](clk))...)
+			c.Put("a", 1)
+			for i, d := range tt.advance {
+				if i > 0 {
+					c.Get("a")
+				}
+				clk.Add(d)
+			}
+			if tt.flush {
+				c.Flush()
+				if got := c.Count(); got != 0 {
+					t.Errorf("Count() = %v, want %v", got, 0)
+				}
+			}
+			if got, _ := c.TTL("a"); got != tt.wantTTL {
+				t.Errorf("TTL() = %v, want %v", got, tt.wantTTL)
+			}
+			if _, got := c.Get("a"); got != tt.want {
+				t.Errorf("Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_Close(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name string
+		c    *Cache[S, T]
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "without janitor",
+			c:    NewCache[string, int](time.Minute),
+		},
+		{
+			name: "with janitor",
+			c:    NewCache[string, int](time.Minute, WithJanitor[string, int](time.Millisecond)),
+		},
+		{
+			name: "struct literal",
+			c:    &Cache[string, int]{defaultTTL: time.Minute, cache: map[string]volatile[int]{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				if err := tt.c.Close(); err != nil {
+					t.Errorf("Close() = %v, want nil", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCache_WithJanitor(t *testing.T) {
+	clk := clock.NewManual(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := NewCache[string, int](time.Minute, WithClock[string, int](clk), WithJanitor[string, int](time.Millisecond))
+	c.Put("a", 1)
+	clk.Add(2 * time.Minute)
+
+	deadline := time.Now().Add(time.Second)
+	for c.Count() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor did not flush the expired entry")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.Close()
+	c.Put("a", 1)
+	clk.Add(2 * time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	if got := c.Count(); got != 1 {
+		t.Errorf("Count() after Close = %v, want %v", got, 1)
+	}
+	if got := c.Stats().Flushes; got != 1 {
+		t.Errorf("Stats().Flushes = %v, want %v", got, 1)
+	}
+}
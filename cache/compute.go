@@ -0,0 +1,77 @@
+package cache
+
+import "github.com/anaregdesign/papaya/model/function"
+
+// GetOrPut returns the live value for key and true if there is one.
+// Otherwise it puts value with the default TTL and returns it and false.
+func (c *Cache[S, T]) GetOrPut(key S, value T) (T, bool) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if v, ok := c.live(key); ok {
+		c.stats.lookup(true)
+		c.touch(key, v)
+		return v.value, true
+	}
+	c.stats.lookup(false)
+	ttl := c.jitter.Apply(c.defaultTTL)
+	c.set(key, value, c.now().Add(ttl), ttl, nil)
+	return value, false
+}
+
+// GetOrCompute returns the live value for key, or else puts and returns the
+// value of supplier with the default TTL. The supplier runs under the lock of
+// the cache, so it is called at most once per missing key and must not call
+// back into the cache.
+func (c *Cache[S, T]) GetOrCompute(key S, supplier function.Supplier[T]) T {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if v, ok := c.live(key); ok {
+		c.stats.lookup(true)
+		c.touch(key, v)
+		return v.value
+	}
+	c.stats.lookup(false)
+	value := supplier()
+	ttl := c.jitter.Apply(c.defaultTTL)
+	c.set(key, value, c.now().Add(ttl), ttl, nil)
+	return value
+}
+
+// Compute replaces the entry for key with the result of remapping, which is
+// given the live value for key and whether there is one. If remapping returns
+// false, the entry is deleted instead. The new entry gets the default TTL
+// and keeps the tags of the old one.
+// Like the supplier of GetOrCompute, remapping runs under the lock of the cache.
+func (c *Cache[S, T]) Compute(key S, remapping func(old T, ok bool) (T, bool)) (T, bool) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	v, ok := c.live(key)
+	value, keep := remapping(v.value, ok)
+	if !keep {
+		c.remove(key, Deleted)
+		c.invalidate(key)
+		var noop T
+		return noop, false
+	}
+	ttl := c.jitter.Apply(c.defaultTTL)
+	c.set(key, value, c.now().Add(ttl), ttl, v.tags)
+	return value, true
+}
+
+// CompareAndSwap puts new for key if the live value for key equals old,
+// keeping the current expiration and tags. As with sync.Map, the values are
+// compared with ==, so T must be comparable at run time or CompareAndSwap panics.
+func (c *Cache[S, T]) CompareAndSwap(key S, old, new T) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
+	v, ok := c.live(key)
+	if !ok || any(v.value) != any(old) {
+		return false
+	}
+	c.set(key, new, v.expiration, v.ttl, v.tags)
+	return true
+}
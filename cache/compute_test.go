@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache_GetOrPut(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name       string
+		cached     map[S]T
+		key        S
+		value      T
+		want       T
+		wantLoaded bool
+	}
+	tests := []testCase[string, int]{
+		{name: "hit", cached: map[string]int{"a": 1}, key: "a", value: 2, want: 1, wantLoaded: true},
+		{name: "miss", key: "a", value: 2, want: 2, wantLoaded: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string, int](time.Minute)
+			for key, value := range tt.cached {
+				c.Put(key, value)
+			}
+			got, loaded := c.GetOrPut(tt.key, tt.value)
+			if got != tt.want || loaded != tt.wantLoaded {
+				t.Errorf("GetOrPut() = %v, %v, want %v, %v", got, loaded, tt.want, tt.wantLoaded)
+			}
+			if got, _ := c.Get(tt.key); got != tt.want {
+				t.Errorf("Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_GetOrCompute(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name      string
+		cached    map[S]T
+		key       S
+		want      T
+		wantCalls int
+	}
+	tests := []testCase[string, int]{
+		{name: "hit", cached: map[string]int{"a": 1}, key: "a", want: 1, wantCalls: 0},
+		{name: "miss", key: "a", want: 2, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string, int](time.Minute)
+			for key, value := range tt.cached {
+				c.Put(key, value)
+			}
+			calls := 0
+			supplier := func() int {
+				calls++
+				return 2
+			}
+			if got := c.GetOrCompute(tt.key, supplier); got != tt.want {
+				t.Errorf("GetOrCompute() = %v, want %v", got, tt.want)
+			}
+			c.GetOrCompute(tt.key, supplier)
+			if calls != tt.wantCalls {
+				t.Errorf("supplier calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCache_Compute(t *testing.T) {
+	increment := func(old int, ok bool) (int, bool) {
+		return old + 1, true
+	}
+	type testCase[S comparable, T any] struct {
+		name      string
+		cached    map[S]T
+		remapping func(old T, ok bool) (T, bool)
+		want      T
+		wantOk    bool
+	}
+	tests := []testCase[string, int]{
+		{name: "absent", remapping: increment, want: 1, wantOk: true},
+		{name: "present", cached: map[string]int{"a": 1}, remapping: increment, want: 2, wantOk: true},
+		{
+			name:   "delete",
+			cached: map[string]int{"a": 1},
+			remapping: func(old int, ok bool) (int, bool) {
+				return 0, false
+			},
+			want:   0,
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string, int](time.Minute)
+			for key, value := range tt.cached {
+				c.Put(key, value)
+			}
+			got, ok := c.Compute("a", tt.remapping)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Compute() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+			if got := c.Has("a"); got != tt.wantOk {
+				t.Errorf("Has() = %v, want %v", got, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestCache_Compute_concurrent(t *testing.T) {
+	c := NewCache[string, int](time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Compute("counter", func(old int, ok bool) (int, bool) {
+				return old + 1, true
+			})
+		}()
+	}
+	wg.Wait()
+	if got, _ := c.Get("counter"); got != 100 {
+		t.Errorf("Get() = %v, want %v", got, 100)
+	}
+}
+
+func TestCache_CompareAndSwap(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name   string
+		cached map[S]T
+		old    T
+		new    T
+		want   bool
+		wantV  T
+	}
+	tests := []testCase[string, int]{
+		{name: "swapped", cached: map[string]int{"a": 1}, old: 1, new: 2, want: true, wantV: 2},
+		{name: "mismatch", cached: map[string]int{"a": 1}, old: 3, new: 2, want: false, wantV: 1},
+		{name: "absent", old: 0, new: 2, want: false, wantV: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string, int](time.Minute)
+			for key, value := range tt.cached {
+				c.Put(key, value)
+			}
+			if got := c.CompareAndSwap("a", tt.old, tt.new); got != tt.want {
+				t.Errorf("CompareAndSwap() = %v, want %v", got, tt.want)
+			}
+			if got, _ := c.Get("a"); got != tt.wantV {
+				t.Errorf("Get() = %v, want %v", got, tt.wantV)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// EventType describes the change to the cache an Event reports.
+type EventType int
+
+const (
+	// EventPut means a value was stored under the key, new or replacing another.
+	EventPut EventType = iota
+	// EventDelete means the key was removed by Delete, InvalidateTag or a replica.
+	EventDelete
+	// EventExpire means the key was removed past its expiration by Get or Flush.
+	EventExpire
+	// EventEvict means the key was removed to keep the cache within its bounds.
+	EventEvict
+	// EventClear means every key was removed by Clear. Its Key and Value are zero.
+	EventClear
+)
+
+func (e EventType) String() string {
+	switch e {
+	case EventPut:
+		return "put"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	case EventClear:
+		return "clear"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change to a Cache, as delivered by Events.
+type Event[S comparable, T any] struct {
+	Type  EventType
+	Key   S
+	Value T
+}
+
+// eventType maps the reason an entry left the cache to the event reporting it.
+func eventType(reason RemovalReason) (EventType, bool) {
+	switch reason {
+	case Expired, Flushed:
+		return EventExpire, true
+	case Deleted, Invalidated:
+		return EventDelete, true
+	case Evicted:
+		return EventEvict, true
+	default:
+		return 0, false
+	}
+}
+
+// subscriber queues the events of one Events channel, so that a slow receiver
+// never blocks writers to the cache.
+type subscriber[S comparable, T any] struct {
+	mu     sync.Mutex
+	queue  []Event[S, T]
+	signal chan struct{}
+}
+
+func (s *subscriber[S, T]) push(e Event[S, T]) {
+	s.mu.Lock()
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber[S, T]) drain() []Event[S, T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue := s.queue
+	s.queue = nil
+	return queue
+}
+
+// Events returns a channel receiving every change made to the cache from now
+// until ctx is done, in the order the changes were made. The channel is closed
+// once ctx is done. Events are queued without bound while the receiver lags.
+func (c *Cache[S, T]) Events(ctx context.Context) <-chan Event[S, T] {
+	s := &subscriber[S, T]{signal: make(chan struct{}, 1)}
+	ch := make(chan Event[S, T])
+
+	c.mu.Lock()
+	c.subscribers = append(c.subscribers, s)
+	c.mu.Unlock()
+
+	go func() {
+		defer close(ch)
+		defer c.unsubscribe(s)
+
+		for {
+			select {
+			case <-s.signal:
+			case <-ctx.Done():
+				return
+			}
+			for _, e := range s.drain() {
+				select {
+				case ch <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+func (c *Cache[S, T]) unsubscribe(s *subscriber[S, T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, sub := range c.subscribers {
+		if sub == s {
+			c.subscribers = append(c.subscribers[:i], c.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// emit queues e for every Events channel. The caller must hold c.mu exclusively.
+func (c *Cache[S, T]) emit(e Event[S, T]) {
+	for _, s := range c.subscribers {
+		s.push(e)
+	}
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCache_Events(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name string
+		opts []Option[S, T]
+		do   func(c *Cache[S, T])
+		want []Event[S, T]
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "put and replace",
+			do: func(c *Cache[string, int]) {
+				c.Put("a", 1)
+				c.Put("a", 2)
+			},
+			want: []Event[string, int]{
+				{Type: EventPut, Key: "a", Value: 1},
+				{Type: EventPut, Key: "a", Value: 2},
+			},
+		},
+		{
+			name: "delete",
+			do: func(c *Cache[string, int]) {
+				c.Put("a", 1)
+				c.Delete("a")
+				c.Delete("b")
+			},
+			want: []Event[string, int]{
+				{Type: EventPut, Key: "a", Value: 1},
+				{Type: EventDelete, Key: "a", Value: 1},
+			},
+		},
+		{
+			name: "expire",
+			do: func(c *Cache[string, int]) {
+				c.PutWithTTL("a", 1, -time.Second)
+				c.Get("a")
+			},
+			want: []Event[string, int]{
+				{Type: EventPut, Key: "a", Value: 1},
+				{Type: EventExpire, Key: "a", Value: 1},
+			},
+		},
+		{
+			name: "evict",
+			opts: []Option[string, int]{WithMaxEntries[string, int](1)},
+			do: func(c *Cache[string, int]) {
+				c.Put("a", 1)
+				c.Put("b", 2)
+			},
+			want: []Event[string, int]{
+				{Type: EventPut, Key: "a", Value: 1},
+				{Type: EventPut, Key: "b", Value: 2},
+				{Type: EventEvict, Key: "a", Value: 1},
+			},
+		},
+		{
+			name: "clear",
+			do: func(c *Cache[string, int]) {
+				c.Put("a", 1)
+				c.Clear()
+			},
+			want: []Event[string, int]{
+				{Type: EventPut, Key: "a", Value: 1},
+				{Type: EventClear},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			c := NewCache[string, int](time.Minute, tt.opts...)
+			events := c.Events(ctx)
+			tt.do(c)
+
+			var got []Event[string, int]
+			for len(got) < len(tt.want) {
+				select {
+				case e := <-events:
+					got = append(got, e)
+				case <-time.After(time.Second):
+					t.Fatalf("Events() = %v, want %v", got, tt.want)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Events() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_Events_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewCache[string, int](time.Minute)
+	events := c.Events(ctx)
+	c.Put("a", 1)
+	cancel()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				c.mu.RLock()
+				n := len(c.subscribers)
+				c.mu.RUnlock()
+				if n != 0 {
+					t.Errorf("len(subscribers) = %v, want %v", n, 0)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Events() channel was not closed")
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package cache
+
+import "time"
+
+// deadline is the position of an entry in the expiration index.
+// Like the elements of the lru list, it holds the key untyped.
+type deadline struct {
+	key        any
+	expiration time.Time
+	index      int
+}
+
+// deadlines is a min-heap of entries ordered by expiration, so that
+// the entries due for removal can be found without scanning the whole cache.
+// It implements heap.Interface.
+type deadlines []*deadline
+
+func (d deadlines) Len() int { return len(d) }
+
+func (d deadlines) Less(i, j int) bool {
+	return d[i].expiration.Before(d[j].expiration)
+}
+
+func (d deadlines) Swap(i, j int) {
+	d[i], d[j] = d[j], d[i]
+	d[i].index = i
+	d[j].index = j
+}
+
+func (d *deadlines) Push(x any) {
+	item := x.(*deadline)
+	item.index = len(*d)
+	*d = append(*d, item)
+}
+
+func (d *deadlines) Pop() any {
+	old := *d
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil  // avoid memory leak
+	item.index = -1 // for safety
+	*d = old[0 : n-1]
+	return item
+}
@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// record is the unit appended to the segment file of a FileStore.
+type record[S comparable, T any] struct {
+	Key        S         `json:"key"`
+	Value      T         `json:"value"`
+	Expiration time.Time `json:"expiration"`
+	Deleted    bool      `json:"deleted,omitempty"`
+}
+
+// location is where the latest record of a key starts in the segment file.
+type location struct {
+	offset     int64
+	expiration time.Time
+}
+
+// FileStore is a Store backed by a single append-only segment file.
+// Every Save or Delete appends a length-prefixed record encoded with its codec,
+// and an in-memory index maps each key to its latest record. The index is
+// rebuilt by scanning the file on open; Compact rewrites the file without
+// the records that are superseded, deleted or expired.
+type FileStore[S comparable, T any] struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	codec Codec
+	ttl   time.Duration
+	index map[S]location
+	size  int64
+}
+
+// OpenFileStore opens or creates the segment file at path. Values saved to the
+// store expire ttl after they are saved; zero or less means they never expire.
+func OpenFileStore[S comparable, T any](path string, codec Codec, ttl time.Duration) (*FileStore[S, T], error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore[S, T]{
+		path:  path,
+		file:  file,
+		codec: codec,
+		ttl:   ttl,
+		index: make(map[S]location),
+	}
+	if err := s.scan(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// scan rebuilds the index from the segment file. A torn record at the end of
+// the file, left by an interrupted write, is truncated away.
+func (s *FileStore[S, T]) scan() error {
+	var offset int64
+	for {
+		rec, n, err := s.read(offset)
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if rec.Deleted {
+			delete(s.index, rec.Key)
+		} else {
+			s.index[rec.Key] = location{offset: offset, expiration: rec.Expiration}
+		}
+		offset += n
+	}
+	s.size = offset
+	return s.file.Truncate(offset)
+}
+
+// read decodes the record at offset and returns it with its length on disk.
+func (s *FileStore[S, T]) read(offset int64) (record[S, T], int64, error) {
+	var rec record[S, T]
+	var header [4]byte
+	if _, err := s.file.ReadAt(header[:], offset); err != nil {
+		return rec, 0, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if _, err := s.file.ReadAt(payload, offset+int64(len(header))); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return rec, 0, err
+	}
+	if err := s.codec.Decode(bytes.NewReader(payload), &rec); err != nil {
+		return rec, 0, err
+	}
+	return rec, int64(len(header) + len(payload)), nil
+}
+
+// append writes rec at the end of the segment file and returns its offset.
+func (s *FileStore[S, T]) append(rec record[S, T]) (int64, error) {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4))
+	if err := s.codec.Encode(&buf, rec); err != nil {
+		return 0, err
+	}
+	b := buf.Bytes()
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+
+	offset := s.size
+	if _, err := s.file.WriteAt(b, offset); err != nil {
+		return 0, err
+	}
+	s.size += int64(len(b))
+	return offset, nil
+}
+
+func (s *FileStore[S, T]) Load(ctx context.Context, key S) (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var noop T
+	loc, ok := s.index[key]
+	if !ok {
+		return noop, ErrNotFound
+	}
+	if !loc.expiration.IsZero() && loc.expiration.Before(time.Now()) {
+		delete(s.index, key)
+		return noop, ErrNotFound
+	}
+	rec, _, err := s.read(loc.offset)
+	if err != nil {
+		return noop, err
+	}
+	return rec.Value, nil
+}
+
+func (s *FileStore[S, T]) Save(ctx context.Context, key S, value T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := record[S, T]{Key: key, Value: value}
+	if s.ttl > 0 {
+		rec.Expiration = time.Now().Add(s.ttl)
+	}
+	offset, err := s.append(rec)
+	if err != nil {
+		return err
+	}
+	s.index[key] = location{offset: offset, expiration: rec.Expiration}
+	return nil
+}
+
+// Delete appends a tombstone for key. It writes nothing when key is not stored.
+func (s *FileStore[S, T]) Delete(ctx context.Context, key S) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.index[key]; !ok {
+		return nil
+	}
+	if _, err := s.append(record[S, T]{Key: key, Deleted: true}); err != nil {
+		return err
+	}
+	delete(s.index, key)
+	return nil
+}
+
+// Count returns the number of keys in the index, including expired ones
+// that have not been looked up or compacted yet.
+func (s *FileStore[S, T]) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.index)
+}
+
+// Compact rewrites the segment file with only the live record of each key.
+func (s *FileStore[S, T]) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.OpenFile(s.path+".compact", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	compacted := &FileStore[S, T]{
+		path:  s.path,
+		file:  tmp,
+		codec: s.codec,
+		ttl:   s.ttl,
+		index: make(map[S]location, len(s.index)),
+	}
+
+	now := time.Now()
+	for key, loc := range s.index {
+		if !loc.expiration.IsZero() && loc.expiration.Before(now) {
+			continue
+		}
+		rec, _, err := s.read(loc.offset)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		offset, err := compacted.append(rec)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		compacted.index[key] = location{offset: offset, expiration: loc.expiration}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	s.file.Close()
+	s.file = compacted.file
+	s.index = compacted.index
+	s.size = compacted.size
+	return nil
+}
+
+func (s *FileStore[S, T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
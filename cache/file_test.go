@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	type testCase[S comparable, T any] struct {
+		name  string
+		codec Codec
+		ttl   time.Duration
+		run   func(s *FileStore[S, T])
+		want  map[S]T
+		gone  []S
+	}
+	tests := []testCase[string, string]{
+		{
+			name:  "save and overwrite",
+			codec: JSON,
+			run: func(s *FileStore[string, string]) {
+				s.Save(ctx, "a", "1")
+				s.Save(ctx, "b", "2")
+				s.Save(ctx, "a", "3")
+			},
+			want: map[string]string{"a": "3", "b": "2"},
+		},
+		{
+			name:  "delete",
+			codec: Gob,
+			run: func(s *FileStore[string, string]) {
+				s.Save(ctx, "a", "1")
+				s.Save(ctx, "b", "2")
+				s.Delete(ctx, "a")
+			},
+			want: map[string]string{"b": "2"},
+			gone: []string{"a"},
+		},
+		{
+			name:  "expired",
+			codec: JSON,
+			ttl:   time.Nanosecond,
+			run: func(s *FileStore[string, string]) {
+				s.Save(ctx, "a", "1")
+			},
+			want: map[string]string{},
+			gone: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "segment")
+			s, err := OpenFileStore[string, string](path, tt.codec, tt.ttl)
+			if err != nil {
+				t.Fatalf("OpenFileStore() error = %v", err)
+			}
+			tt.run(s)
+			s.Close()
+
+			// the index must survive a reopen, and again a compaction
+			for _, stage := range []string{"reopen", "compact"} {
+				s, err = OpenFileStore[string, string](path, tt.codec, tt.ttl)
+				if err != nil {
+					t.Fatalf("OpenFileStore() error = %v", err)
+				}
+				if stage == "compact" {
+					if err := s.Compact(); err != nil {
+						t.Fatalf("Compact() error = %v", err)
+					}
+				}
+				for key, want := range tt.want {
+					if got, err := s.Load(ctx, key); err != nil || got != want {
+						t.Errorf("%v: Load(%v) = %v, %v, want %v", stage, key, got, err, want)
+					}
+				}
+				for _, key := range tt.gone {
+					if _, err := s.Load(ctx, key); !errors.Is(err, ErrNotFound) {
+						t.Errorf("%v: Load(%v) error = %v, want %v", stage, key, err, ErrNotFound)
+					}
+				}
+				s.Close()
+			}
+		})
+	}
+}
+
+func TestFileStore_scan_truncated(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "segment")
+	s, err := OpenFileStore[string, int](path, JSON, 0)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	s.Save(ctx, "a", 1)
+	s.Save(ctx, "b", 2)
+	size := s.size
+	s.Close()
+
+	// simulate a write torn by a crash
+	if err := os.Truncate(path, size-3); err != nil {
+		t.Fatal(err)
+	}
+	s, err = OpenFileStore[string, int](path, JSON, 0)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	defer s.Close()
+	if got := s.Count(); got != 1 {
+		t.Errorf("Count() = %v, want %v", got, 1)
+	}
+	if err := s.Save(ctx, "b", 3); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if got, _ := s.Load(ctx, "b"); got != 3 {
+		t.Errorf("Load() = %v, want %v", got, 3)
+	}
+}
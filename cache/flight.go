@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errLoadPanicked is what the callers waiting on a load get when the load panicked.
+var errLoadPanicked = errors.New("cache: load panicked")
+
+// call is a load in flight for a single key, shared by every caller waiting on it.
+type call[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+	// panicked is what the load panicked with, if it did.
+	panicked any
+}
+
+// group deduplicates concurrent loads of the same key,
+// so that only one of them runs and the others share its result.
+type group[S comparable, T any] struct {
+	mu    sync.Mutex
+	calls map[S]*call[T]
+}
+
+// do runs fn for key in the background unless a call for key is already in
+// flight, and waits for that call. Every caller, including the one that started
+// the call, gives up when its own ctx is done, without cancelling the call, so
+// fn should run under a context that outlives its callers. If fn panics, the
+// panic goes up the caller that started the call and the other callers get
+// errLoadPanicked.
+func (g *group[S, T]) do(ctx context.Context, key S, fn func() (T, error)) (T, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[S]*call[T])
+	}
+	c, waiting := g.calls[key]
+	if !waiting {
+		c = &call[T]{done: make(chan struct{}), err: errLoadPanicked}
+		g.calls[key] = c
+		go g.call(key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		if !waiting && c.panicked != nil {
+			panic(c.panicked)
+		}
+		return c.value, c.err
+	case <-ctx.Done():
+		var noop T
+		return noop, ctx.Err()
+	}
+}
+
+// call runs fn for the call c of key and settles c with its result.
+func (g *group[S, T]) call(key S, c *call[T], fn func() (T, error)) {
+	defer func() {
+		c.panicked = recover()
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.value, c.err = fn()
+}
+
+// doAll is do for several keys at once: fn runs once for the keys that have no
+// call in flight and returns the values it found, while the keys that do are
+// waited for. Keys fn leaves out fail with ErrNotFound. It returns the values
+// found for keys; waiting on the calls of others stops when ctx is done.
+func (g *group[S, T]) doAll(ctx context.Context, keys []S, fn func(keys []S) map[S]T) map[S]T {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[S]*call[T])
+	}
+	owned := make([]S, 0, len(keys))
+	calls := make(map[S]*call[T], len(keys))
+	waiting := make(map[S]*call[T])
+	for _, key := range keys {
+		if c, ok := g.calls[key]; ok {
+			waiting[key] = c
+			continue
+		}
+		c := &call[T]{done: make(chan struct{}), err: errLoadPanicked}
+		g.calls[key] = c
+		calls[key] = c
+		owned = append(owned, key)
+	}
+	g.mu.Unlock()
+
+	values := make(map[S]T, len(keys))
+	if len(owned) > 0 {
+		g.run(owned, calls, fn, values)
+	}
+	for key, c := range waiting {
+		select {
+		case <-c.done:
+			if c.err == nil {
+				values[key] = c.value
+			}
+		case <-ctx.Done():
+			return values
+		}
+	}
+	return values
+}
+
+// run calls fn for keys, settles their calls with its result and copies the
+// values found into values.
+func (g *group[S, T]) run(keys []S, calls map[S]*call[T], fn func(keys []S) map[S]T, values map[S]T) {
+	defer func() {
+		g.mu.Lock()
+		for key := range calls {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		for _, c := range calls {
+			close(c.done)
+		}
+	}()
+
+	loaded := fn(keys)
+	for key, c := range calls {
+		if value, ok := loaded[key]; ok {
+			c.value, c.err = value, nil
+			values[key] = value
+		} else {
+			c.err = ErrNotFound
+		}
+	}
+}
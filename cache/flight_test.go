@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_group_do_panic(t *testing.T) {
+	var g group[string, int]
+	started := make(chan struct{})
+	release := make(chan struct{})
+	panicked := make(chan any)
+	go func() {
+		defer func() {
+			panicked <- recover()
+		}()
+		g.do(context.Background(), "a", func() (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	type result struct {
+		value int
+		err   error
+	}
+	waiter := make(chan result)
+	go func() {
+		value, err := g.do(context.Background(), "a", func() (int, error) {
+			return 1, nil
+		})
+		waiter <- result{value, err}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if got := <-panicked; got != "boom" {
+		t.Errorf("recover() = %v, want %v", got, "boom")
+	}
+	if got := <-waiter; !errors.Is(got.err, errLoadPanicked) {
+		t.Errorf("do() = %v, %v, want %v", got.value, got.err, errLoadPanicked)
+	}
+	if value, err := g.do(context.Background(), "a", func() (int, error) { return 2, nil }); value != 2 || err != nil {
+		t.Errorf("do() after panic = %v, %v, want %v, nil", value, err, 2)
+	}
+}
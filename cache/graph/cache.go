@@ -3,6 +3,7 @@ package graph
 import (
 	"context"
 	"github.com/anaregdesign/papaya/cache"
+	"github.com/anaregdesign/papaya/clock"
 	"github.com/anaregdesign/papaya/collection/pq"
 	"github.com/anaregdesign/papaya/collection/set"
 	"github.com/anaregdesign/papaya/graph"
@@ -14,16 +15,42 @@ import (
 type GraphCache[S comparable, T any] struct {
 	mu         sync.RWMutex
 	defaultTTL time.Duration
+	clock      clock.Clock
+	jitter     cache.Jitter
 	vertices   *cache.Cache[S, T]
 	edges      *edgeCache[S]
+
+	// once, done and stopped stop the janitor started by WithJanitor.
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
 }
 
-func NewGraphCache[S comparable, T any](defaultTTL time.Duration) *GraphCache[S, T] {
-	return &GraphCache[S, T]{
+func NewGraphCache[S comparable, T any](defaultTTL time.Duration, opts ...Option) *GraphCache[S, T] {
+	o := newOptions(opts)
+	edges := newEdgeCache[S](defaultTTL)
+	edges.clock = o.clock
+	c := &GraphCache[S, T]{
 		defaultTTL: defaultTTL,
-		vertices:   cache.NewCache[S, T](defaultTTL),
-		edges:      newEdgeCache[S](defaultTTL),
+		clock:      o.clock,
+		jitter:     o.jitter,
+		vertices:   cache.NewCache[S, T](defaultTTL, cache.WithClock[S, T](o.clock)),
+		edges:      edges,
+	}
+	if o.janitor > 0 {
+		c.done = make(chan struct{})
+		c.stopped = make(chan struct{})
+		go c.janitor(o.janitor)
+	}
+	return c
+}
+
+// now returns the current time by the clock of the cache.
+func (c *GraphCache[S, T]) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
 	}
+	return c.clock.Now()
 }
 
 func (c *GraphCache[S, T]) GetVertex(key S) (T, bool) {
@@ -48,7 +75,7 @@ func (c *GraphCache[S, T]) AddVertexWithExpiration(key S, value T, expiration ti
 }
 
 func (c *GraphCache[S, T]) AddVertexWithTTL(key S, value T, ttl time.Duration) {
-	c.AddVertexWithExpiration(key, value, time.Now().Add(ttl))
+	c.AddVertexWithExpiration(key, value, c.now().Add(c.jitter.Apply(ttl)))
 }
 
 func (c *GraphCache[S, T]) PutVertex(key S, value T) {
@@ -70,7 +97,7 @@ func (c *GraphCache[S, T]) AddEdgeWithExpiration(tail, head S, w float32, expira
 }
 
 func (c *GraphCache[S, T]) AddEdgeWithTTL(tail, head S, w float32, ttl time.Duration) {
-	c.AddEdgeWithExpiration(tail, head, w, time.Now().Add(ttl))
+	c.AddEdgeWithExpiration(tail, head, w, c.now().Add(c.jitter.Apply(ttl)))
 }
 
 func (c *GraphCache[S, T]) AddEdge(tail, head S, w float32) {
@@ -133,12 +160,13 @@ func (c *GraphCache[S, T]) Neighbor(seed S, step int, k int, tfidf bool) *graph.
 				defer wg.Done()
 				// Add edges to the graph
 				edges := pq.SortableMap[S, float32]{}
+				now := c.edges.now()
 				for head, w := range c.edges.getTF()[t] {
 					if tfidf {
 						df := c.edges.getDF()[head]
-						edges[head] = w.value() / float32(math.Log2(float64(1+df)))
+						edges[head] = w.value(now) / float32(math.Log2(float64(1+df)))
 					} else {
-						edges[head] = w.value()
+						edges[head] = w.value(now)
 					}
 				}
 
@@ -185,12 +213,47 @@ func (c *GraphCache[S, T]) Watch(ctx context.Context, interval time.Duration) {
 	for {
 		select {
 		case <-ticker.C:
-			c.vertices.Flush()
-			c.edges.flush()
-			c.flush()
+			c.sweep()
 
 		case <-ctx.Done():
 			return
 		}
 	}
 }
+
+// Close stops the janitor started by WithJanitor and waits for it to return.
+// It is safe to call more than once, and on a cache without a janitor.
+func (c *GraphCache[S, T]) Close() error {
+	c.once.Do(func() {
+		if c.done != nil {
+			close(c.done)
+		}
+	})
+	if c.stopped != nil {
+		<-c.stopped
+	}
+	return nil
+}
+
+func (c *GraphCache[S, T]) janitor(interval time.Duration) {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.sweep()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// sweep removes expired vertices, expired edges and the edges left dangling
+// by removed vertices.
+func (c *GraphCache[S, T]) sweep() {
+	c.vertices.Flush()
+	c.edges.flush()
+	c.flush()
+}
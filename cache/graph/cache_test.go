@@ -3,6 +3,7 @@ package graph
 import (
 	"context"
 	"github.com/anaregdesign/papaya/cache"
+	"github.com/anaregdesign/papaya/clock"
 	"github.com/anaregdesign/papaya/graph"
 	"reflect"
 	"testing"
@@ -587,3 +588,103 @@ func TestGraphCache_DeleteEdge(t *testing.T) {
 		})
 	}
 }
+
+func TestGraphCache_WithClock(t *testing.T) {
+	type testCase struct {
+		name       string
+		advance    time.Duration
+		wantVertex bool
+		wantWeight float32
+		wantEdge   bool
+	}
+	tests := []testCase{
+		{
+			name:       "live until the ttl passes",
+			advance:    30 * time.Second,
+			wantVertex: true,
+			wantWeight: 3,
+			wantEdge:   true,
+		},
+		{
+			name:       "short weight expires first",
+			advance:    90 * time.Second,
+			wantVertex: true,
+			wantWeight: 2,
+			wantEdge:   true,
+		},
+		{
+			name:    "expired once the ttl passes",
+			advance: 3 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewManual(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+			c := NewGraphCache[string, string](2*time.Minute, WithClock(clk))
+			c.PutVertex("tail", "a")
+			c.PutVertex("head", "b")
+			c.AddEdge("tail", "head", 2)
+			c.AddEdgeWithTTL("tail", "head", 1, time.Minute)
+			clk.Add(tt.advance)
+
+			if _, ok := c.GetVertex("tail"); ok != tt.wantVertex {
+				t.Errorf("GetVertex() = %v, want %v", ok, tt.wantVertex)
+			}
+			got, ok := c.GetWeight("tail", "head")
+			if got != tt.wantWeight || ok != tt.wantEdge {
+				t.Errorf("GetWeight() = %v, %v, want %v, %v", got, ok, tt.wantWeight, tt.wantEdge)
+			}
+		})
+	}
+}
+
+func TestGraphCache_Close(t *testing.T) {
+	clk := clock.NewManual(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := NewGraphCache[string, string](time.Minute, WithClock(clk), WithJanitor(time.Millisecond))
+	c.PutVertex("tail", "a")
+	c.PutVertex("head", "b")
+	c.AddEdge("tail", "head", 1)
+	clk.Add(2 * time.Minute)
+
+	edges := func() int {
+		c.edges.mu.RLock()
+		defer c.edges.mu.RUnlock()
+
+		return len(c.edges.tf)
+	}
+	deadline := time.Now().Add(time.Second)
+	for edges() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor did not remove the expired edge")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.Close()
+	c.Close()
+	c.AddEdge("tail", "head", 1)
+	clk.Add(2 * time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	if got := edges(); got != 1 {
+		t.Errorf("len(tf) after Close = %v, want %v", got, 1)
+	}
+}
+
+func TestGraphCache_WithJitter(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewGraphCache[string, string](time.Minute, WithClock(clock.NewManual(start)), WithJitter(cache.JitterRange(10*time.Second)))
+	for i := 0; i < 100; i++ {
+		c.AddEdgeWithTTL("tail", "head", 1, time.Minute)
+	}
+
+	expirations := make(map[time.Time]struct{})
+	for _, v := range c.edges.tf["tail"]["head"].values {
+		if v.expiration.Before(start.Add(50*time.Second)) || v.expiration.After(start.Add(time.Minute)) {
+			t.Fatalf("expiration = %v, want in [%v, %v]", v.expiration, start.Add(50*time.Second), start.Add(time.Minute))
+		}
+		expirations[v.expiration] = struct{}{}
+	}
+	if len(expirations) < 2 {
+		t.Errorf("expirations are not spread: %v", expirations)
+	}
+}
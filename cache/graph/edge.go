@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"github.com/anaregdesign/papaya/clock"
 	"sync"
 	"time"
 )
@@ -11,8 +12,8 @@ type weightValue struct {
 	expiration time.Time
 }
 
-func (w weightValue) expired() bool {
-	return time.Now().After(w.expiration)
+func (w weightValue) expired(now time.Time) bool {
+	return now.After(w.expiration)
 }
 
 type weight struct {
@@ -25,8 +26,8 @@ func newWeight() *weight {
 	}
 }
 
-func (w *weight) value() float32 {
-	w.flush()
+func (w *weight) value(now time.Time) float32 {
+	w.flush(now)
 	var sum float32
 	for _, v := range w.values {
 		sum += v.value
@@ -41,18 +42,18 @@ func (w *weight) addWithExpiration(value float32, expiration time.Time) {
 	})
 }
 
-func (w *weight) addWithTTL(value float32, ttl time.Duration) {
-	w.addWithExpiration(value, time.Now().Add(ttl))
+func (w *weight) addWithTTL(value float32, ttl time.Duration, now time.Time) {
+	w.addWithExpiration(value, now.Add(ttl))
 }
 
-func (w *weight) isZero() bool {
-	return w.value() == 0
+func (w *weight) isZero(now time.Time) bool {
+	return w.value(now) == 0
 }
 
-func (w *weight) flush() {
+func (w *weight) flush(now time.Time) {
 	v := make([]weightValue, 0)
 	for _, value := range w.values {
-		if !value.expired() {
+		if !value.expired(now) {
 			v = append(v, value)
 		}
 	}
@@ -62,6 +63,7 @@ func (w *weight) flush() {
 type edgeCache[S comparable] struct {
 	mu         sync.RWMutex
 	defaultTTL time.Duration
+	clock      clock.Clock
 	tf         map[S]map[S]*weight
 	df         map[S]int
 }
@@ -86,14 +88,23 @@ func (c *edgeCache[S]) get(tail, head S) (float32, bool) {
 		return 0, false
 	}
 
-	if w := c.tf[tail][head]; w.isZero() {
+	now := c.now()
+	if w := c.tf[tail][head]; w.isZero(now) {
 		go c.delete(tail, head)
 		return 0, false
 	} else {
-		return w.value(), true
+		return w.value(now), true
 	}
 }
 
+// now returns the current time by the clock of the cache.
+func (c *edgeCache[S]) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
 func (c *edgeCache[S]) getTF() map[S]map[S]*weight {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -125,7 +136,7 @@ func (c *edgeCache[S]) addWithExpiration(tail, head S, w float32, expiration tim
 }
 
 func (c *edgeCache[S]) addWithTTL(tail, head S, w float32, ttl time.Duration) {
-	c.addWithExpiration(tail, head, w, time.Now().Add(ttl))
+	c.addWithExpiration(tail, head, w, c.now().Add(ttl))
 }
 
 func (c *edgeCache[S]) add(tail, head S, w float32) {
@@ -158,9 +169,10 @@ func (c *edgeCache[S]) flush() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	now := c.now()
 	for tail, heads := range c.tf {
 		for head, w := range heads {
-			if w.isZero() {
+			if w.isZero(now) {
 				c.df[head]--
 				if c.df[head] <= 0 {
 					delete(c.df, head)
@@ -176,6 +188,8 @@ func (c *edgeCache[S]) flush() {
 
 func (c *edgeCache[S]) watch(ctx context.Context, interval time.Duration) {
 	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
@@ -52,7 +52,7 @@ func Test_weightValue_expired(t *testing.T) {
 				value:      tt.fields.value,
 				expiration: tt.fields.ttl,
 			}
-			if got := w.expired(); got != tt.want {
+			if got := w.expired(time.Now()); got != tt.want {
 				t.Errorf("expired() = %v, want %v", got, tt.want)
 			}
 		})
@@ -88,7 +88,7 @@ func Test_weight_add(t *testing.T) {
 			w := &weight{
 				values: tt.fields.values,
 			}
-			w.addWithTTL(tt.args.value, tt.args.ttl)
+			w.addWithTTL(tt.args.value, tt.args.ttl, time.Now())
 		})
 	}
 }
@@ -124,7 +124,7 @@ func Test_weight_value(t *testing.T) {
 			w := &weight{
 				values: tt.fields.values,
 			}
-			if got := w.value(); got != tt.want {
+			if got := w.value(time.Now()); got != tt.want {
 				t.Errorf("value() = %v, want %v", got, tt.want)
 			}
 		})
@@ -170,7 +170,7 @@ func Test_weight_isZero(t *testing.T) {
 			w := &weight{
 				values: tt.fields.values,
 			}
-			if got := w.isZero(); got != tt.want {
+			if got := w.isZero(time.Now()); got != tt.want {
 				t.Errorf("isZero() = %v, want %v", got, tt.want)
 			}
 		})
@@ -406,7 +406,7 @@ func Test_weight_addWithTTL(t *testing.T) {
 			w := &weight{
 				values: tt.fields.values,
 			}
-			w.addWithTTL(tt.args.value, tt.args.ttl)
+			w.addWithTTL(tt.args.value, tt.args.ttl, time.Now())
 		})
 	}
 }
@@ -0,0 +1,48 @@
+package graph
+
+import (
+	"github.com/anaregdesign/papaya/cache"
+	"github.com/anaregdesign/papaya/clock"
+	"time"
+)
+
+type options struct {
+	clock   clock.Clock
+	janitor time.Duration
+	jitter  cache.Jitter
+}
+
+// Option configures a GraphCache at construction time.
+type Option func(*options)
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithClock makes the cache tell the expiration of vertices and edges by c
+// instead of the system clock, typically a clock.Manual in tests.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithJanitor makes the cache remove its expired vertices and edges every
+// interval in the background, as Watch would, until it is closed.
+func WithJanitor(interval time.Duration) Option {
+	return func(o *options) {
+		o.janitor = interval
+	}
+}
+
+// WithJitter makes the cache shorten the TTL of every vertex and edge added
+// with a TTL by j, so that those added together expire apart.
+func WithJitter(j cache.Jitter) Option {
+	return func(o *options) {
+		o.jitter = j
+	}
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+)
+
+var seed = maphash.MakeSeed()
+
+// hash spreads keys of any comparable type over uint64.
+// Strings, booleans and numbers are hashed directly, with the two zeros of
+// floating point keys hashed alike as they are equal map keys. Other keys are
+// hashed through their printed form, which allocates on every call and hashes
+// apart keys that are equal but print differently, such as structs holding
+// -0.0 and 0.0; such keys need a hasher given by WithHasher.
+func hash[S comparable](key S) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(seed, k)
+	case int:
+		return mix(uint64(k))
+	case int8:
+		return mix(uint64(k))
+	case int16:
+		return mix(uint64(k))
+	case int32:
+		return mix(uint64(k))
+	case int64:
+		return mix(uint64(k))
+	case uint:
+		return mix(uint64(k))
+	case uint8:
+		return mix(uint64(k))
+	case uint16:
+		return mix(uint64(k))
+	case uint32:
+		return mix(uint64(k))
+	case uint64:
+		return mix(k)
+	case uintptr:
+		return mix(uint64(k))
+	case bool:
+		if k {
+			return mix(1)
+		}
+		return mix(0)
+	case float32:
+		return hashFloat(float64(k))
+	case float64:
+		return hashFloat(k)
+	case complex64:
+		return hashFloat(real(complex128(k))) ^ mix(hashFloat(imag(complex128(k))))
+	case complex128:
+		return hashFloat(real(k)) ^ mix(hashFloat(imag(k)))
+	default:
+		return maphash.String(seed, fmt.Sprintf("%#v", key))
+	}
+}
+
+// hashFloat hashes f by its bits, after folding -0 into 0.
+func hashFloat(f float64) uint64 {
+	if f == 0 {
+		f = 0
+	}
+	return mix(math.Float64bits(f))
+}
+
+// mix is the splitmix64 finalizer.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
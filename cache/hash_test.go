@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_hash(t *testing.T) {
+	negativeZero := math.Copysign(0, -1)
+	type testCase struct {
+		name string
+		a, b uint64
+	}
+	tests := []testCase{
+		{
+			name: "float64 zeros",
+			a:    hash(negativeZero),
+			b:    hash(0.0),
+		},
+		{
+			name: "float32 zeros",
+			a:    hash(float32(negativeZero)),
+			b:    hash(float32(0)),
+		},
+		{
+			name: "complex128 zeros",
+			a:    hash(complex(negativeZero, negativeZero)),
+			b:    hash(complex(0, 0)),
+		},
+		{
+			name: "bool",
+			a:    hash(true),
+			b:    hash(true),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.a != tt.b {
+				t.Errorf("hash() = %v and %v, want equal", tt.a, tt.b)
+			}
+		})
+	}
+	if hash(1.5) == hash(2.5) {
+		t.Errorf("hash(1.5) = hash(2.5)")
+	}
+	if allocs := testing.AllocsPerRun(100, func() { hash(1.5) }); allocs != 0 {
+		t.Errorf("hash(1.5) allocates %v times, want 0", allocs)
+	}
+}
@@ -0,0 +1,299 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/anaregdesign/papaya/cache"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response is a cached HTTP response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type options struct {
+	vary []string
+}
+
+// Option configures the Middleware.
+type Option func(*options)
+
+// WithVary adds request headers to the cache key, so that requests for the same
+// URL that differ in those headers are cached separately.
+func WithVary(headers ...string) Option {
+	return func(o *options) {
+		o.vary = append(o.vary, headers...)
+	}
+}
+
+// Middleware caches the successful responses to GET requests in c, keyed by
+// method, host, URL and the headers given by WithVary. Responses are cached for
+// their Cache-Control s-maxage or max-age, or the default TTL of c without
+// either. They are not cached with no-store or private, with Set-Cookie, or
+// with a Vary header listing a header not given to WithVary, and responses to
+// requests with Authorization are only cached when they are public or carry
+// s-maxage. Requests with no-store bypass the cache,
+// and requests with no-cache skip the lookup but refresh the cached response.
+// Every cached response carries an ETag, computed from its body unless the
+// handler sets one, and a request whose If-None-Match matches it gets a 304.
+// Responses that cannot be cached are passed through to the client as the
+// handler writes them, without being buffered.
+func Middleware(c *cache.Cache[string, *Response], opts ...Option) func(http.Handler) http.Handler {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqCC := parseCacheControl(r.Header.Get("Cache-Control"))
+			if r.Method != http.MethodGet || reqCC.noStore {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := o.key(r)
+			auth := r.Header.Get("Authorization") != ""
+			if !reqCC.noCache {
+				if res, ok := c.Get(key); ok && (!auth || shared(res)) {
+					w.Header().Set("X-Cache", "HIT")
+					write(w, r, res)
+					return
+				}
+			}
+
+			rec := &recorder{
+				w:      w,
+				header: make(http.Header),
+				status: http.StatusOK,
+				vary:   o.vary,
+				auth:   auth,
+			}
+			next.ServeHTTP(rec, r)
+			if rec.passed {
+				return
+			}
+			if !rec.wrote && !rec.decide() {
+				return
+			}
+			res := &Response{Status: rec.status, Header: rec.header, Body: rec.body.Bytes()}
+			if res.Header.Get("ETag") == "" {
+				res.Header.Set("ETag", etag(res.Body))
+			}
+			if ttl := rec.cc.ttl(); ttl > 0 {
+				c.PutWithTTL(key, res, ttl)
+			} else {
+				c.Put(key, res)
+			}
+			w.Header().Set("X-Cache", "MISS")
+			write(w, r, res)
+		})
+	}
+}
+
+func (o *options) key(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte(' ')
+	b.WriteString(r.Host)
+	b.WriteByte(' ')
+	b.WriteString(r.URL.String())
+	for _, h := range o.vary {
+		b.WriteByte('\n')
+		b.WriteString(http.CanonicalHeaderKey(h))
+		b.WriteByte(':')
+		b.WriteString(strings.Join(r.Header.Values(h), ","))
+	}
+	return b.String()
+}
+
+// write sends res to w, or a 304 when the request already holds its ETag.
+func write(w http.ResponseWriter, r *http.Request, res *Response) {
+	for k, v := range res.Header {
+		w.Header()[k] = v
+	}
+	if tag := res.Header.Get("ETag"); tag != "" && matches(r.Header.Get("If-None-Match"), tag) {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(res.Status)
+	w.Write(res.Body)
+}
+
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matches reports whether the If-None-Match header value lists tag.
+func matches(ifNoneMatch string, tag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+type cacheControl struct {
+	noStore bool
+	noCache bool
+	private bool
+	public  bool
+	// maxAge and sMaxAge are negative when their directive is absent.
+	maxAge  time.Duration
+	sMaxAge time.Duration
+}
+
+// ttl returns how long a shared cache may keep the response: its s-maxage,
+// else its max-age, and a negative duration without either.
+func (cc cacheControl) ttl() time.Duration {
+	if cc.sMaxAge >= 0 {
+		return cc.sMaxAge
+	}
+	return cc.maxAge
+}
+
+func parseCacheControl(value string) cacheControl {
+	cc := cacheControl{maxAge: -1, sMaxAge: -1}
+	for _, directive := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			cc.noStore = true
+		case "no-cache":
+			cc.noCache = true
+		case "private":
+			cc.private = true
+		case "public":
+			cc.public = true
+		case "max-age":
+			cc.maxAge = parseSeconds(arg, cc.maxAge)
+		case "s-maxage":
+			cc.sMaxAge = parseSeconds(arg, cc.sMaxAge)
+		}
+	}
+	return cc
+}
+
+// parseSeconds parses the delta-seconds argument of a directive, or returns
+// fallback when it is not one.
+func parseSeconds(arg string, fallback time.Duration) time.Duration {
+	seconds, err := strconv.Atoi(strings.Trim(arg, `"`))
+	if err != nil || seconds < 0 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// shared reports whether res may be served to requests with Authorization.
+func shared(res *Response) bool {
+	cc := parseCacheControl(res.Header.Get("Cache-Control"))
+	return cc.public || cc.sMaxAge >= 0
+}
+
+// varies reports whether the Vary header value lists a header that is not in
+// the cache key, so that the response may differ between requests sharing it.
+func varies(vary []string, keyed []string) bool {
+	for _, value := range vary {
+		for _, h := range strings.Split(value, ",") {
+			h = strings.TrimSpace(h)
+			if h == "" {
+				continue
+			}
+			if h == "*" || !contains(keyed, h) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(headers []string, h string) bool {
+	for _, candidate := range headers {
+		if strings.EqualFold(candidate, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// recorder buffers the response of the wrapped handler while it may be cached,
+// and passes it through to w once its status or headers show it may not.
+type recorder struct {
+	w      http.ResponseWriter
+	header http.Header
+	status int
+	cc     cacheControl
+	vary   []string
+	auth   bool
+	body   bytes.Buffer
+	wrote  bool
+	passed bool
+}
+
+func (r *recorder) Header() http.Header {
+	if r.passed {
+		return r.w.Header()
+	}
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.wrote {
+		return
+	}
+	r.status = status
+	r.wrote = true
+	r.decide()
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if !r.wrote {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.passed {
+		return r.w.Write(b)
+	}
+	return r.body.Write(b)
+}
+
+// cacheable reports whether the response may be stored and shared.
+func (r *recorder) cacheable() bool {
+	switch {
+	case r.status != http.StatusOK, r.cc.noStore, r.cc.private, r.cc.ttl() == 0:
+		return false
+	case r.header.Get("Set-Cookie") != "":
+		return false
+	case varies(r.header.Values("Vary"), r.vary):
+		return false
+	case r.auth && !r.cc.public && r.cc.sMaxAge < 0:
+		return false
+	}
+	return true
+}
+
+// decide reports whether the response may be cached, and otherwise sends its
+// status and headers to w so that the rest of it passes through.
+func (r *recorder) decide() bool {
+	r.cc = parseCacheControl(r.header.Get("Cache-Control"))
+	if r.cacheable() {
+		return true
+	}
+	for k, v := range r.header {
+		r.w.Header()[k] = v
+	}
+	r.w.Header().Set("X-Cache", "MISS")
+	r.w.WriteHeader(r.status)
+	r.passed = true
+	return false
+}
@@ -0,0 +1,295 @@
+package httpcache
+
+import (
+	"github.com/anaregdesign/papaya/cache"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	type request struct {
+		method string
+		target string
+		host   string
+		header map[string]string
+	}
+	type testCase struct {
+		name         string
+		opts         []Option
+		cacheControl string
+		requests     []request
+		wantStatus   []int
+		wantCalls    int
+	}
+	get := func(target string) request {
+		return request{method: http.MethodGet, target: target}
+	}
+	tests := []testCase{
+		{
+			name:       "repeated get is served from the cache",
+			requests:   []request{get("/a"), get("/a"), get("/b")},
+			wantStatus: []int{200, 200, 200},
+			wantCalls:  2,
+		},
+		{
+			name:       "post is not cached",
+			requests:   []request{{method: http.MethodPost, target: "/a"}, {method: http.MethodPost, target: "/a"}},
+			wantStatus: []int{200, 200},
+			wantCalls:  2,
+		},
+		{
+			name:         "response no-store is not cached",
+			cacheControl: "no-store",
+			requests:     []request{get("/a"), get("/a")},
+			wantStatus:   []int{200, 200},
+			wantCalls:    2,
+		},
+		{
+			name:         "response max-age=0 is not cached",
+			cacheControl: "max-age=0",
+			requests:     []request{get("/a"), get("/a")},
+			wantStatus:   []int{200, 200},
+			wantCalls:    2,
+		},
+		{
+			name:         "response max-age is cached",
+			cacheControl: "public, max-age=60",
+			requests:     []request{get("/a"), get("/a")},
+			wantStatus:   []int{200, 200},
+			wantCalls:    1,
+		},
+		{
+			name: "request no-store bypasses the cache",
+			requests: []request{
+				get("/a"),
+				{method: http.MethodGet, target: "/a", header: map[string]string{"Cache-Control": "no-store"}},
+			},
+			wantStatus: []int{200, 200},
+			wantCalls:  2,
+		},
+		{
+			name: "matching etag is not modified",
+			requests: []request{
+				get("/a"),
+				{method: http.MethodGet, target: "/a", header: map[string]string{"If-None-Match": `"v1"`}},
+				{method: http.MethodGet, target: "/a", header: map[string]string{"If-None-Match": `"v0"`}},
+			},
+			wantStatus: []int{200, 304, 200},
+			wantCalls:  1,
+		},
+		{
+			name: "vary headers split the key",
+			opts: []Option{WithVary("Accept-Language")},
+			requests: []request{
+				{method: http.MethodGet, target: "/a", header: map[string]string{"Accept-Language": "en"}},
+				{method: http.MethodGet, target: "/a", header: map[string]string{"Accept-Language": "ja"}},
+				{method: http.MethodGet, target: "/a", header: map[string]string{"Accept-Language": "en"}},
+			},
+			wantStatus: []int{200, 200, 200},
+			wantCalls:  2,
+		},
+		{
+			name: "hosts split the key",
+			requests: []request{
+				{method: http.MethodGet, target: "/a", host: "a.example.com"},
+				{method: http.MethodGet, target: "/a", host: "b.example.com"},
+				{method: http.MethodGet, target: "/a", host: "a.example.com"},
+			},
+			wantStatus: []int{200, 200, 200},
+			wantCalls:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.Header().Set("ETag", `"v1"`)
+				if tt.cacheControl != "" {
+					w.Header().Set("Cache-Control", tt.cacheControl)
+				}
+				w.Write([]byte(`{"vertices":{}}`))
+			})
+			c := cache.NewCache[string, *Response](time.Minute)
+			h := Middleware(c, tt.opts...)(handler)
+
+			for i, req := range tt.requests {
+				r := httptest.NewRequest(req.method, req.target, nil)
+				if req.host != "" {
+					r.Host = req.host
+				}
+				for k, v := range req.header {
+					r.Header.Set(k, v)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				if w.Code != tt.wantStatus[i] {
+					t.Errorf("request %v: status = %v, want %v", i, w.Code, tt.wantStatus[i])
+				}
+				if w.Code == http.StatusOK && w.Body.String() != `{"vertices":{}}` {
+					t.Errorf("request %v: body = %v", i, w.Body.String())
+				}
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMiddleware_etag(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	})
+	h := Middleware(cache.NewCache[string, *Response](time.Minute))(handler)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	tag := w.Header().Get("ETag")
+	if tag == "" {
+		t.Fatalf("ETag is empty")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", tag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("status = %v, want %v", w.Code, http.StatusNotModified)
+	}
+	if got := w.Header().Get("X-Cache"); got != "HIT" {
+		t.Errorf("X-Cache = %v, want %v", got, "HIT")
+	}
+}
+
+func TestMiddleware_passThrough(t *testing.T) {
+	type testCase struct {
+		name         string
+		status       int
+		cacheControl string
+	}
+	tests := []testCase{
+		{name: "no-store", status: http.StatusOK, cacheControl: "no-store"},
+		{name: "private", status: http.StatusOK, cacheControl: "private"},
+		{name: "not found", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				if tt.cacheControl != "" {
+					rw.Header().Set("Cache-Control", tt.cacheControl)
+				}
+				rw.WriteHeader(tt.status)
+				rw.Write([]byte("first"))
+				if got := w.Body.String(); got != "first" {
+					t.Errorf("body before the handler returned = %q, want %q", got, "first")
+				}
+				rw.Write([]byte(" second"))
+			})
+			h := Middleware(cache.NewCache[string, *Response](time.Minute))(handler)
+
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %v, want %v", w.Code, tt.status)
+			}
+			if got := w.Body.String(); got != "first second" {
+				t.Errorf("body = %q, want %q", got, "first second")
+			}
+			if got := w.Header().Get("X-Cache"); got != "MISS" {
+				t.Errorf("X-Cache = %v, want %v", got, "MISS")
+			}
+		})
+	}
+}
+
+func TestMiddleware_shared(t *testing.T) {
+	type testCase struct {
+		name     string
+		opts     []Option
+		header   map[string]string
+		requests []map[string]string
+		want     []string
+		wantHits []string
+	}
+	alice := map[string]string{"Authorization": "alice", "Accept-Language": "en"}
+	bob := map[string]string{"Authorization": "bob", "Accept-Language": "ja"}
+	en := map[string]string{"User": "alice", "Accept-Language": "en"}
+	ja := map[string]string{"User": "bob", "Accept-Language": "ja"}
+	tests := []testCase{
+		{
+			name:     "set-cookie is not cached",
+			header:   map[string]string{"Set-Cookie": "session=user"},
+			requests: []map[string]string{en, ja},
+			want:     []string{"alice", "bob"},
+			wantHits: []string{"MISS", "MISS"},
+		},
+		{
+			name:     "authorized response is not shared",
+			requests: []map[string]string{alice, bob},
+			want:     []string{"alice", "bob"},
+			wantHits: []string{"MISS", "MISS"},
+		},
+		{
+			name:     "authorized public response is shared",
+			header:   map[string]string{"Cache-Control": "public"},
+			requests: []map[string]string{alice, bob},
+			want:     []string{"alice", "alice"},
+			wantHits: []string{"MISS", "HIT"},
+		},
+		{
+			name:     "authorized s-maxage response is shared",
+			header:   map[string]string{"Cache-Control": "s-maxage=60"},
+			requests: []map[string]string{alice, bob},
+			want:     []string{"alice", "alice"},
+			wantHits: []string{"MISS", "HIT"},
+		},
+		{
+			name:     "vary outside the key is not cached",
+			header:   map[string]string{"Vary": "Accept-Language"},
+			requests: []map[string]string{en, ja},
+			want:     []string{"alice", "bob"},
+			wantHits: []string{"MISS", "MISS"},
+		},
+		{
+			name:     "vary within the key is cached",
+			opts:     []Option{WithVary("accept-language")},
+			header:   map[string]string{"Vary": "Accept-Language"},
+			requests: []map[string]string{en, ja, en},
+			want:     []string{"alice", "bob", "alice"},
+			wantHits: []string{"MISS", "MISS", "HIT"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				user := r.Header.Get("Authorization")
+				if user == "" {
+					user = r.Header.Get("User")
+				}
+				w.Write([]byte(user))
+			})
+			h := Middleware(cache.NewCache[string, *Response](time.Minute), tt.opts...)(handler)
+
+			for i, header := range tt.requests {
+				r := httptest.NewRequest(http.MethodGet, "/me", nil)
+				for k, v := range header {
+					r.Header.Set(k, v)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				if got := w.Body.String(); got != tt.want[i] {
+					t.Errorf("request %v: body = %v, want %v", i, got, tt.want[i])
+				}
+				if got := w.Header().Get("X-Cache"); got != tt.wantHits[i] {
+					t.Errorf("request %v: X-Cache = %v, want %v", i, got, tt.wantHits[i])
+				}
+			}
+		})
+	}
+}
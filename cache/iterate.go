@@ -0,0 +1,89 @@
+package cache
+
+import "time"
+
+// Entries returns the live entries of the cache, in no particular order.
+func (c *Cache[S, T]) Entries() []Entry[S, T] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.now()
+	entries := make([]Entry[S, T], 0, len(c.cache))
+	for k, v := range c.cache {
+		if !v.expired(now) {
+			entries = append(entries, Entry[S, T]{Key: k, Value: v.value, Expiration: v.expiration})
+		}
+	}
+	return entries
+}
+
+// Keys returns the keys of the live entries of the cache, in no particular order.
+func (c *Cache[S, T]) Keys() []S {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.now()
+	keys := make([]S, 0, len(c.cache))
+	for k, v := range c.cache {
+		if !v.expired(now) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Range calls f for each live entry with its key, value and expiration,
+// until f returns false. It iterates over a copy of the entries,
+// so f may call back into the cache.
+func (c *Cache[S, T]) Range(f func(key S, value T, expiration time.Time) bool) {
+	for _, e := range c.Entries() {
+		if !f(e.Key, e.Value, e.Expiration) {
+			return
+		}
+	}
+}
+
+// TTL returns the remaining lifetime of the live entry for key.
+func (c *Cache[S, T]) TTL(key S) (time.Duration, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	v, ok := c.cache[key]
+	now := c.now()
+	if !ok || v.expired(now) {
+		return 0, false
+	}
+	return v.expiration.Sub(now), true
+}
+
+// Entries returns the live entries of all shards, in no particular order.
+func (c *ShardedCache[S, T]) Entries() []Entry[S, T] {
+	entries := make([]Entry[S, T], 0)
+	for _, s := range c.shards {
+		entries = append(entries, s.Entries()...)
+	}
+	return entries
+}
+
+// Keys returns the keys of the live entries of all shards, in no particular order.
+func (c *ShardedCache[S, T]) Keys() []S {
+	keys := make([]S, 0)
+	for _, s := range c.shards {
+		keys = append(keys, s.Keys()...)
+	}
+	return keys
+}
+
+// Range calls f for each live entry of every shard until f returns false.
+func (c *ShardedCache[S, T]) Range(f func(key S, value T, expiration time.Time) bool) {
+	for _, e := range c.Entries() {
+		if !f(e.Key, e.Value, e.Expiration) {
+			return
+		}
+	}
+}
+
+// TTL returns the remaining lifetime of the live entry for key.
+func (c *ShardedCache[S, T]) TTL(key S) (time.Duration, bool) {
+	return c.shard(key).TTL(key)
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"github.com/anaregdesign/papaya/clock"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCache_Keys(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name string
+		ttls map[S]time.Duration
+		want []S
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "expired entries are skipped",
+			ttls: map[string]time.Duration{"a": time.Minute, "b": -time.Second, "c": time.Hour},
+			want: []string{"a", "c"},
+		},
+		{
+			name: "empty",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string, int](time.Minute)
+			for key, ttl := range tt.ttls {
+				c.PutWithTTL(key, 1, ttl)
+			}
+
+			keys := c.Keys()
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("Keys() = %v, want %v", keys, tt.want)
+			}
+
+			entries := make([]string, 0)
+			for _, e := range c.Entries() {
+				entries = append(entries, e.Key)
+			}
+			sort.Strings(entries)
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Errorf("Entries() = %v, want %v", entries, tt.want)
+			}
+
+			ranged := make([]string, 0)
+			c.Range(func(key string, value int, expiration time.Time) bool {
+				ranged = append(ranged, key)
+				c.Get(key)
+				return true
+			})
+			sort.Strings(ranged)
+			if !reflect.DeepEqual(ranged, tt.want) {
+				t.Errorf("Range() = %v, want %v", ranged, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_Range_stop(t *testing.T) {
+	c := NewCache[string, int](time.Minute)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	calls := 0
+	c.Range(func(key string, value int, expiration time.Time) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("calls = %v, want %v", calls, 1)
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name   string
+		ttl    time.Duration
+		key    S
+		wantOk bool
+	}
+	tests := []testCase[string, int]{
+		{name: "live", ttl: time.Minute, key: "a", wantOk: true},
+		{name: "expired", ttl: -time.Second, key: "a", wantOk: false},
+		{name: "absent", ttl: time.Minute, key: "b", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string, int](time.Minute)
+			c.PutWithTTL("a", 1, tt.ttl)
+			got, ok := c.TTL(tt.key)
+			if ok != tt.wantOk {
+				t.Errorf("TTL() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && (got <= 0 || got > tt.ttl) {
+				t.Errorf("TTL() = %v, want in (0, %v]", got, tt.ttl)
+			}
+		})
+	}
+}
+
+func TestCache_Keys_expiration(t *testing.T) {
+	clk := clock.NewManual(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := NewCache[string, int](time.Minute, WithClock[string, int](clk))
+	c.Put("a", 1)
+	clk.Add(time.Minute)
+
+	// At its expiration exactly, the entry is still live to every API.
+	if got := c.Keys(); len(got) != 1 {
+		t.Errorf("Keys() = %v, want [a]", got)
+	}
+	if got := c.Entries(); len(got) != 1 {
+		t.Errorf("Entries() = %v, want one entry", got)
+	}
+	if _, ok := c.TTL("a"); !ok {
+		t.Errorf("TTL(a) ok = false, want true")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("Get(a) ok = false, want true")
+	}
+}
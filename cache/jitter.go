@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Jitter shortens a TTL by a random amount, so that entries put together do
+// not all expire together. It never lengthens a TTL, so an entry never
+// outlives the TTL it was put with.
+type Jitter func(ttl time.Duration) time.Duration
+
+// JitterPercent shortens a TTL by up to percent of itself, so that
+// JitterPercent(10) spreads a TTL of a minute over [54s, 1m]. A percent
+// outside [0, 100] is clamped to it.
+func JitterPercent(percent float64) Jitter {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	return func(ttl time.Duration) time.Duration {
+		return ttl - time.Duration(rand.Float64()*percent/100*float64(ttl))
+	}
+}
+
+// JitterRange shortens a TTL by up to d, and by no more than the TTL itself.
+// A negative d is clamped to zero.
+func JitterRange(d time.Duration) Jitter {
+	if d < 0 {
+		d = 0
+	}
+	return func(ttl time.Duration) time.Duration {
+		spread := d
+		if spread > ttl {
+			spread = ttl
+		}
+		return ttl - time.Duration(rand.Float64()*float64(spread))
+	}
+}
+
+// Apply returns ttl shortened by j. A nil Jitter, or a ttl that has already
+// run out, leaves ttl unchanged.
+func (j Jitter) Apply(ttl time.Duration) time.Duration {
+	if j == nil || ttl <= 0 {
+		return ttl
+	}
+	return j(ttl)
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/anaregdesign/papaya/clock"
+	"testing"
+	"time"
+)
+
+func TestJitter_Apply(t *testing.T) {
+	type testCase struct {
+		name string
+		j    Jitter
+		ttl  time.Duration
+		min  time.Duration
+		max  time.Duration
+	}
+	tests := []testCase{
+		{
+			name: "nil",
+			ttl:  time.Minute,
+			min:  time.Minute,
+			max:  time.Minute,
+		},
+		{
+			name: "percent",
+			j:    JitterPercent(10),
+			ttl:  time.Minute,
+			min:  54 * time.Second,
+			max:  time.Minute,
+		},
+		{
+			name: "percent over 100 is clamped",
+			j:    JitterPercent(150),
+			ttl:  time.Minute,
+			min:  0,
+			max:  time.Minute,
+		},
+		{
+			name: "negative percent is clamped",
+			j:    JitterPercent(-10),
+			ttl:  time.Minute,
+			min:  time.Minute,
+			max:  time.Minute,
+		},
+		{
+			name: "range",
+			j:    JitterRange(5 * time.Second),
+			ttl:  time.Minute,
+			min:  55 * time.Second,
+			max:  time.Minute,
+		},
+		{
+			name: "range longer than ttl",
+			j:    JitterRange(time.Hour),
+			ttl:  time.Minute,
+			min:  0,
+			max:  time.Minute,
+		},
+		{
+			name: "negative range is clamped",
+			j:    JitterRange(-10 * time.Second),
+			ttl:  time.Minute,
+			min:  time.Minute,
+			max:  time.Minute,
+		},
+		{
+			name: "expired ttl",
+			j:    JitterPercent(10),
+			ttl:  -time.Second,
+			min:  -time.Second,
+			max:  -time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 1000; i++ {
+				if got := tt.j.Apply(tt.ttl); got < tt.min || got > tt.max {
+					t.Fatalf("Apply(%v) = %v, want in [%v, %v]", tt.ttl, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestCache_WithJitter(t *testing.T) {
+	clk := clock.NewManual(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	loader := func(key string) (int, bool) {
+		return len(key), true
+	}
+	type testCase struct {
+		name string
+		put  func(key string) *Cache[string, int]
+	}
+	c := NewCache[string, int](time.Minute, WithClock[string, int](clk), WithJitter[string, int](JitterPercent(50)))
+	l := NewLoadingCache[string, int](context.Background(), loader, time.Minute, WithClock[string, int](clk), WithJitter[string, int](JitterPercent(50)))
+	tests := []testCase{
+		{
+			name: "Put",
+			put: func(key string) *Cache[string, int] {
+				c.Put(key, 1)
+				return c
+			},
+		},
+		{
+			name: "LoadingCache.Get",
+			put: func(key string) *Cache[string, int] {
+				l.Get(key)
+				return l.cache
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttls := make(map[time.Duration]struct{})
+			for i := 0; i < 100; i++ {
+				key := fmt.Sprint(i)
+				got, ok := tt.put(key).TTL(key)
+				if !ok || got < 30*time.Second || got > time.Minute {
+					t.Fatalf("TTL(%v) = %v, %v, want in [30s, 1m]", key, got, ok)
+				}
+				ttls[got] = struct{}{}
+			}
+			if len(ttls) < 2 {
+				t.Errorf("TTLs are not spread: %v", ttls)
+			}
+		})
+	}
+}
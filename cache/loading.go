@@ -2,38 +2,230 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"github.com/anaregdesign/papaya/model/function"
+	"sync"
 	"time"
 )
 
+// ErrNotFound is returned by a LoadingCache, and may be returned by a
+// function.ContextLoader, when no value exists for a key.
+var ErrNotFound = errors.New("cache: not found")
+
 type LoadingCache[S comparable, T any] struct {
-	cache  *Cache[S, T]
-	loader function.Loader[S, T]
+	ctx          context.Context
+	cache        *Cache[S, T]
+	loader       function.ContextLoader[S, T]
+	batchLoader  function.BatchLoader[S, T]
+	flight       group[S, T]
+	refreshAfter time.Duration
+
+	// negatives remembers keys the loader reported as not found.
+	// It is nil unless negative caching is enabled.
+	negatives *Cache[S, struct{}]
+
+	mu         sync.Mutex
+	refreshing map[S]struct{}
 }
 
-func NewLoadingCache[S comparable, T any](ctx context.Context, loader function.Loader[S, T], defaultTTL time.Duration) *LoadingCache[S, T] {
-	return &LoadingCache[S, T]{
-		cache:  NewCache[S, T](defaultTTL),
-		loader: loader,
+func NewLoadingCache[S comparable, T any](ctx context.Context, loader function.Loader[S, T], defaultTTL time.Duration, opts ...Option[S, T]) *LoadingCache[S, T] {
+	return NewContextLoadingCache[S, T](ctx, func(_ context.Context, key S) (T, error) {
+		if value, ok := loader(key); ok {
+			return value, nil
+		}
+		var noop T
+		return noop, ErrNotFound
+	}, defaultTTL, opts...)
+}
+
+// NewContextLoadingCache is NewLoadingCache for a loader that takes a context
+// and reports failures as errors. The loader should return ErrNotFound when
+// no value exists for a key, which distinguishes it from a transient failure.
+func NewContextLoadingCache[S comparable, T any](ctx context.Context, loader function.ContextLoader[S, T], defaultTTL time.Duration, opts ...Option[S, T]) *LoadingCache[S, T] {
+	o := newOptions(opts)
+	c := &LoadingCache[S, T]{
+		ctx:          ctx,
+		cache:        NewCache[S, T](defaultTTL, opts...),
+		loader:       loader,
+		batchLoader:  o.batchLoader,
+		refreshAfter: o.refreshAfter,
+		refreshing:   make(map[S]struct{}),
+	}
+	if o.negativeTTL > 0 {
+		c.negatives = NewCache[S, struct{}](o.negativeTTL,
+			WithClock[S, struct{}](o.clock),
+			WithMaxEntries[S, struct{}](o.maxEntries),
+			WithJanitor[S, struct{}](o.janitor),
+		)
 	}
+	return c
 }
 
 func (c *LoadingCache[S, T]) Get(key S) (T, bool) {
+	value, err := c.GetContext(c.ctx, key)
+	return value, err == nil
+}
+
+// GetContext returns the cached value for key, loading it on a miss.
+// It returns ErrNotFound when the loader has no value for key, and the loader's
+// error when the load fails. The load runs under the context of the cache and is
+// shared with concurrent callers, so a caller giving up when ctx is done gets
+// the error of ctx without cancelling the load for the others.
+func (c *LoadingCache[S, T]) GetContext(ctx context.Context, key S) (T, error) {
 	if value, ok := c.cache.Get(key); ok {
-		return value, true
+		if c.stale(key) {
+			c.refresh(key)
+		}
+		return value, nil
 	}
-	if value, ok := c.loader(key); ok {
-		c.cache.Put(key, value)
-		return value, true
+	if c.negatives != nil && c.negatives.Has(key) {
+		var noop T
+		return noop, ErrNotFound
 	}
-	var noop T
-	return noop, false
+	return c.flight.do(ctx, key, func() (T, error) {
+		return c.load(c.ctx, key)
+	})
+}
+
+// GetAll returns the values for keys, serving hits from the cache and loading
+// all misses at once through the batch loader, or one by one through the loader
+// when no batch loader is configured. Keys without a value are absent from the result.
+func (c *LoadingCache[S, T]) GetAll(keys []S) map[S]T {
+	values := make(map[S]T, len(keys))
+	misses := make([]S, 0)
+	seen := make(map[S]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		if value, ok := c.cache.Get(key); ok {
+			if c.stale(key) {
+				c.refresh(key)
+			}
+			values[key] = value
+		} else if c.negatives == nil || !c.negatives.Has(key) {
+			misses = append(misses, key)
+		}
+	}
+	if len(misses) == 0 {
+		return values
+	}
+
+	if c.batchLoader == nil {
+		for _, key := range misses {
+			key := key
+			value, err := c.flight.do(c.ctx, key, func() (T, error) {
+				return c.load(c.ctx, key)
+			})
+			if err == nil {
+				values[key] = value
+			}
+		}
+		return values
+	}
+
+	loaded := c.flight.doAll(c.ctx, misses, c.loadAll)
+	for key, value := range loaded {
+		values[key] = value
+	}
+	return values
+}
+
+// loadAll loads keys through the batch loader, caching the values found and,
+// with negative caching, the keys that were not.
+func (c *LoadingCache[S, T]) loadAll(keys []S) map[S]T {
+	start := time.Now()
+	loaded := c.batchLoader(keys)
+	c.cache.stats.loadBatch(time.Since(start), len(loaded), len(keys)-len(loaded))
+	for _, key := range keys {
+		if value, ok := loaded[key]; ok {
+			c.cache.Put(key, value)
+		} else if c.negatives != nil {
+			c.negatives.Put(key, struct{}{})
+		}
+	}
+	return loaded
+}
+
+func (c *LoadingCache[S, T]) load(ctx context.Context, key S) (T, error) {
+	start := time.Now()
+	value, err := c.loader(ctx, key)
+	c.cache.stats.load(time.Since(start), err == nil)
+	if err != nil {
+		if c.negatives != nil && errors.Is(err, ErrNotFound) {
+			c.negatives.Put(key, struct{}{})
+		}
+		var noop T
+		return noop, err
+	}
+	c.cache.Put(key, value)
+	return value, nil
+}
+
+// stale reports whether the entry for key is due for a background refresh.
+func (c *LoadingCache[S, T]) stale(key S) bool {
+	if c.refreshAfter <= 0 {
+		return false
+	}
+	written, ok := c.cache.writtenAt(key)
+	return ok && c.cache.now().Sub(written) > c.refreshAfter
+}
+
+// refresh reloads key in the background, unless a refresh of key is already running
+// or the context of the cache is done. A failed reload keeps the current value.
+func (c *LoadingCache[S, T]) refresh(key S) {
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	c.mu.Lock()
+	if _, ok := c.refreshing[key]; ok {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = struct{}{}
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+		c.flight.do(c.ctx, key, func() (T, error) {
+			return c.load(c.ctx, key)
+		})
+	}()
 }
 
 func (c *LoadingCache[S, T]) Set(key S, value T) {
+	c.forget(key)
 	c.cache.Put(key, value)
 }
 
 func (c *LoadingCache[S, T]) SetWithTTL(key S, value T, ttl time.Duration) {
+	c.forget(key)
 	c.cache.PutWithTTL(key, value, ttl)
 }
+
+// forget drops key from the negative cache once a value is known for it.
+func (c *LoadingCache[S, T]) forget(key S) {
+	if c.negatives != nil {
+		c.negatives.Delete(key)
+	}
+}
+
+// Stats returns a snapshot of the lookup, removal and load counters of the cache.
+func (c *LoadingCache[S, T]) Stats() Stats {
+	return c.cache.Stats()
+}
+
+// Close stops the janitors of the cache and of its negative cache, as Cache.Close does.
+func (c *LoadingCache[S, T]) Close() error {
+	if c.negatives != nil {
+		c.negatives.Close()
+	}
+	return c.cache.Close()
+}
@@ -0,0 +1,482 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/anaregdesign/papaya/clock"
+	"github.com/anaregdesign/papaya/model/function"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadingCache_Stats(t *testing.T) {
+	loader := func(key string) (int, bool) {
+		return len(key), key != ""
+	}
+	type testCase[S comparable, T any] struct {
+		name string
+		keys []S
+		want Stats
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "loads once then hits",
+			keys: []string{"a", "a", "a"},
+			want: Stats{Hits: 2, Misses: 1, Loads: 1},
+		},
+		{
+			name: "load failures",
+			keys: []string{"", ""},
+			want: Stats{Misses: 2, LoadFailures: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLoadingCache[string, int](context.Background(), loader, time.Minute)
+			for _, key := range tt.keys {
+				c.Get(key)
+			}
+			got := c.Stats()
+			got.LoadTime = 0
+			if got != tt.want {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadingCache_Get_concurrent(t *testing.T) {
+	type testCase struct {
+		name    string
+		callers int
+		want    int64
+	}
+	tests := []testCase{
+		{
+			name:    "one load for many callers",
+			callers: 100,
+			want:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			release := make(chan struct{})
+			loader := func(key string) (int, bool) {
+				calls.Add(1)
+				<-release
+				return 1, true
+			}
+			c := NewLoadingCache[string, int](context.Background(), loader, time.Minute)
+
+			var wg sync.WaitGroup
+			for i := 0; i < tt.callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if got, ok := c.Get("a"); !ok || got != 1 {
+						t.Errorf("Get() = %v, %v, want %v, %v", got, ok, 1, true)
+					}
+				}()
+			}
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			if got := calls.Load(); got != tt.want {
+				t.Errorf("loader calls = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadingCache_Get_refresh(t *testing.T) {
+	type testCase struct {
+		name         string
+		refreshAfter time.Duration
+		wait         time.Duration
+		want         int64
+	}
+	tests := []testCase{
+		{
+			name:         "fresh entry is not reloaded",
+			refreshAfter: time.Minute,
+			want:         1,
+		},
+		{
+			name:         "stale entry is served and reloaded",
+			refreshAfter: 10 * time.Millisecond,
+			wait:         20 * time.Millisecond,
+			want:         2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			loader := func(key string) (int64, bool) {
+				return calls.Add(1), true
+			}
+			c := NewLoadingCache[string, int64](context.Background(), loader, time.Minute, WithRefreshAfter[string, int64](tt.refreshAfter))
+
+			c.Get("a")
+			time.Sleep(tt.wait)
+			if got, _ := c.Get("a"); got != 1 {
+				t.Errorf("Get() = %v, want %v", got, 1)
+			}
+
+			deadline := time.Now().Add(time.Second)
+			for calls.Load() < tt.want && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(5 * time.Millisecond)
+			if got := calls.Load(); got != tt.want {
+				t.Errorf("loader calls = %v, want %v", got, tt.want)
+			}
+			if got, _ := c.Get("a"); got != tt.want {
+				t.Errorf("Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadingCache_GetContext(t *testing.T) {
+	errTimeout := errors.New("timeout")
+	type testCase[S comparable, T any] struct {
+		name      string
+		loader    function.ContextLoader[S, T]
+		opts      []Option[S, T]
+		key       S
+		want      T
+		wantErr   error
+		wantCalls int64
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "found",
+			loader: func(ctx context.Context, key string) (int, error) {
+				return 1, nil
+			},
+			key:       "a",
+			want:      1,
+			wantCalls: 1,
+		},
+		{
+			name: "not found is reloaded",
+			loader: func(ctx context.Context, key string) (int, error) {
+				return 0, ErrNotFound
+			},
+			key:       "a",
+			wantErr:   ErrNotFound,
+			wantCalls: 3,
+		},
+		{
+			name: "not found is cached negatively",
+			loader: func(ctx context.Context, key string) (int, error) {
+				return 0, ErrNotFound
+			},
+			opts:      []Option[string, int]{WithNegativeTTL[string, int](time.Minute)},
+			key:       "a",
+			wantErr:   ErrNotFound,
+			wantCalls: 1,
+		},
+		{
+			name: "failure is not cached negatively",
+			loader: func(ctx context.Context, key string) (int, error) {
+				return 0, errTimeout
+			},
+			opts:      []Option[string, int]{WithNegativeTTL[string, int](time.Minute)},
+			key:       "a",
+			wantErr:   errTimeout,
+			wantCalls: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			loader := func(ctx context.Context, key string) (int, error) {
+				calls.Add(1)
+				return tt.loader(ctx, key)
+			}
+			c := NewContextLoadingCache[string, int](context.Background(), loader, time.Minute, tt.opts...)
+			for i := 0; i < 3; i++ {
+				got, err := c.GetContext(context.Background(), tt.key)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GetContext() error = %v, want %v", err, tt.wantErr)
+				}
+				if got != tt.want {
+					t.Errorf("GetContext() got = %v, want %v", got, tt.want)
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("loader calls = %v, want %v", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestLoadingCache_GetContext_cancelled(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		close(started)
+		select {
+		case <-release:
+			return 1, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	c := NewContextLoadingCache[string, int](context.Background(), loader, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := c.GetContext(ctx, "a")
+		first <- err
+	}()
+	<-started
+
+	type result struct {
+		value int
+		err   error
+	}
+	second := make(chan result)
+	go func() {
+		value, err := c.GetContext(context.Background(), "a")
+		second <- result{value, err}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("GetContext() of the cancelled caller = %v, want %v", err, context.Canceled)
+	}
+	close(release)
+	if got := <-second; got.value != 1 || got.err != nil {
+		t.Errorf("GetContext() of the live caller = %v, %v, want %v, nil", got.value, got.err, 1)
+	}
+}
+
+func TestLoadingCache_GetAll(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name        string
+		batch       bool
+		cached      map[S]T
+		keys        []S
+		want        map[S]T
+		wantBatches [][]S
+	}
+	tests := []testCase[string, int]{
+		{
+			name:        "misses are loaded in one batch",
+			batch:       true,
+			cached:      map[string]int{"a": 1},
+			keys:        []string{"a", "bb", "ccc", "bb", ""},
+			want:        map[string]int{"a": 1, "bb": 2, "ccc": 3},
+			wantBatches: [][]string{{"bb", "ccc", ""}},
+		},
+		{
+			name:        "all hits skip the batch loader",
+			batch:       true,
+			cached:      map[string]int{"a": 1, "b": 2},
+			keys:        []string{"a", "b"},
+			want:        map[string]int{"a": 1, "b": 2},
+			wantBatches: nil,
+		},
+		{
+			name:   "falls back to the loader",
+			cached: map[string]int{"a": 1},
+			keys:   []string{"a", "bb", ""},
+			want:   map[string]int{"a": 1, "bb": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := func(key string) (int, bool) {
+				return len(key), key != ""
+			}
+			var batches [][]string
+			var opts []Option[string, int]
+			if tt.batch {
+				opts = append(opts, WithBatchLoader[string, int](func(keys []string) map[string]int {
+					batches = append(batches, keys)
+					values := make(map[string]int)
+					for _, key := range keys {
+						if value, ok := loader(key); ok {
+							values[key] = value
+						}
+					}
+					return values
+				}))
+			}
+			c := NewLoadingCache[string, int](context.Background(), loader, time.Minute, opts...)
+			for key, value := range tt.cached {
+				c.Set(key, value)
+			}
+
+			if got := c.GetAll(tt.keys); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAll() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(batches, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", batches, tt.wantBatches)
+			}
+			for key := range tt.want {
+				if !c.cache.Has(key) {
+					t.Errorf("Has(%v) = false, want true", key)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadingCache_GetAll_stats(t *testing.T) {
+	loader := func(key string) (int, bool) {
+		return len(key), key != ""
+	}
+	batchLoader := func(keys []string) map[string]int {
+		values := make(map[string]int)
+		for _, key := range keys {
+			if value, ok := loader(key); ok {
+				values[key] = value
+			}
+		}
+		return values
+	}
+	type testCase[S comparable, T any] struct {
+		name string
+		opts []Option[S, T]
+		want Stats
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "loader",
+			want: Stats{Misses: 3, Loads: 2, LoadFailures: 1},
+		},
+		{
+			name: "batch loader",
+			opts: []Option[string, int]{WithBatchLoader[string, int](batchLoader)},
+			want: Stats{Misses: 3, Loads: 2, LoadFailures: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLoadingCache[string, int](context.Background(), loader, time.Minute, tt.opts...)
+			c.GetAll([]string{"a", "bb", ""})
+			got := c.Stats()
+			got.LoadTime = 0
+			if got != tt.want {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadingCache_GetAll_concurrent(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	loader := func(key string) (int, bool) {
+		calls.Add(1)
+		close(started)
+		<-release
+		return len(key), true
+	}
+	var batches [][]string
+	batchLoader := func(keys []string) map[string]int {
+		batches = append(batches, keys)
+		values := make(map[string]int)
+		for _, key := range keys {
+			values[key] = len(key)
+		}
+		return values
+	}
+	c := NewLoadingCache[string, int](context.Background(), loader, time.Minute, WithBatchLoader[string, int](batchLoader))
+
+	go c.Get("a")
+	<-started
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+
+	want := map[string]int{"a": 1, "bb": 2}
+	if got := c.GetAll([]string{"a", "bb"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAll() = %v, want %v", got, want)
+	}
+	if want := [][]string{{"bb"}}; !reflect.DeepEqual(batches, want) {
+		t.Errorf("batches = %v, want %v", batches, want)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("loader calls = %v, want %v", got, 1)
+	}
+}
+
+func TestLoadingCache_WithClock(t *testing.T) {
+	clk := clock.NewManual(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	var loads atomic.Int32
+	loader := func(key string) (int, bool) {
+		return int(loads.Add(1)), true
+	}
+	c := NewLoadingCache[string, int](context.Background(), loader, time.Minute, WithClock[string, int](clk))
+
+	type testCase struct {
+		name    string
+		advance time.Duration
+		want    int
+	}
+	tests := []testCase{
+		{name: "first get loads", want: 1},
+		{name: "live entry is cached", advance: 59 * time.Second, want: 1},
+		{name: "expired entry is reloaded", advance: 2 * time.Second, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk.Add(tt.advance)
+			if got, _ := c.Get("a"); got != tt.want {
+				t.Errorf("Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadingCache_negatives(t *testing.T) {
+	clk := clock.NewManual(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	loader := func(key string) (int, bool) {
+		return 0, false
+	}
+	c := NewLoadingCache[string, int](context.Background(), loader, time.Minute,
+		WithClock[string, int](clk),
+		WithNegativeTTL[string, int](time.Minute),
+		WithMaxEntries[string, int](2),
+		WithJanitor[string, int](time.Millisecond),
+	)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		c.Get(key)
+	}
+	if got := c.negatives.Count(); got != 2 {
+		t.Errorf("negatives.Count() = %v, want %v", got, 2)
+	}
+
+	clk.Add(2 * time.Minute)
+	deadline := time.Now().Add(time.Second)
+	for c.negatives.Count() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor did not flush the negative cache")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := c.Close(); err != nil {
+			t.Errorf("Close() = %v, want nil", err)
+		}
+	}
+	c.Get("a")
+	clk.Add(2 * time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	if got := c.negatives.Count(); got != 1 {
+		t.Errorf("negatives.Count() after Close = %v, want %v", got, 1)
+	}
+}
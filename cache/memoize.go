@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/anaregdesign/papaya/model/function"
+	"time"
+)
+
+// Memoize returns a function that caches the results of fn for ttl.
+// Concurrent calls with the same argument share a single call to fn,
+// and options such as WithMaxEntries bound the memory it holds.
+// fn should be pure, since a cached result stands in for calling it again.
+// If fn panics, every call sharing it panics as well. WithJanitor is ignored,
+// since the returned function cannot be closed to stop the janitor.
+func Memoize[S comparable, T any](fn function.Function[S, T], ttl time.Duration, opts ...Option[S, T]) function.Function[S, T] {
+	opts = append(opts[:len(opts):len(opts)], func(o *options[S, T]) {
+		o.janitor = 0
+	})
+	c := NewLoadingCache[S, T](context.Background(), func(key S) (T, bool) {
+		return fn(key), true
+	}, ttl, opts...)
+
+	return func(key S) T {
+		for {
+			// A call that shared a panicked one runs fn again, and so panics
+			// itself unless fn has recovered in the meantime.
+			value, err := c.GetContext(context.Background(), key)
+			if !errors.Is(err, errLoadPanicked) {
+				return value
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoize(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name      string
+		opts      []Option[S, T]
+		args      []S
+		wantCalls int64
+	}
+	tests := []testCase[int, int]{
+		{
+			name:      "repeated arguments are cached",
+			args:      []int{1, 2, 1, 2, 1},
+			wantCalls: 2,
+		},
+		{
+			name:      "max entries evicts old results",
+			opts:      []Option[int, int]{WithMaxEntries[int, int](1)},
+			args:      []int{1, 2, 1},
+			wantCalls: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			square := Memoize[int, int](func(x int) int {
+				calls.Add(1)
+				return x * x
+			}, time.Minute, tt.opts...)
+
+			for _, x := range tt.args {
+				if got := square(x); got != x*x {
+					t.Errorf("square(%v) = %v, want %v", x, got, x*x)
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %v, want %v", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMemoize_concurrent(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	slow := Memoize[int, int](func(x int) int {
+		calls.Add(1)
+		<-release
+		return x
+	}, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slow(1)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %v, want %v", got, 1)
+	}
+}
+
+func TestMemoize_panic(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	boom := Memoize[int, int](func(x int) int {
+		once.Do(func() {
+			close(started)
+		})
+		<-release
+		panic("boom")
+	}, time.Minute)
+
+	panicked := make(chan any, 2)
+	call := func() {
+		defer func() {
+			panicked <- recover()
+		}()
+		boom(1)
+	}
+	go call()
+	<-started
+	go call()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if got := <-panicked; got != "boom" {
+			t.Errorf("recover() = %v, want %v", got, "boom")
+		}
+	}
+}
+
+func TestMemoize_WithJanitor(t *testing.T) {
+	before := runtime.NumGoroutine()
+	square := Memoize[int, int](func(x int) int {
+		return x * x
+	}, time.Minute, WithJanitor[int, int](time.Millisecond))
+	square(2)
+
+	// The load of square(2) runs in a goroutine that may take a moment to exit.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := runtime.NumGoroutine(); got > before {
+		t.Errorf("NumGoroutine() = %v, want at most %v", got, before)
+	}
+}
@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"github.com/anaregdesign/papaya/clock"
+	"github.com/anaregdesign/papaya/model/function"
+	"time"
+)
+
+type options[S comparable, T any] struct {
+	maxEntries int
+	maxCost    int64
+	cost       func(T) int64
+	listeners  []RemovalListener[S, T]
+	spill      spillListener[S, T]
+	sliding    bool
+	admission  bool
+	clock      clock.Clock
+	janitor    time.Duration
+	jitter     Jitter
+	hasher     func(S) uint64
+
+	refreshAfter time.Duration
+	negativeTTL  time.Duration
+	batchLoader  function.BatchLoader[S, T]
+}
+
+// Option configures a Cache or a LoadingCache at construction time.
+// Options that only apply to a LoadingCache are ignored by NewCache.
+type Option[S comparable, T any] func(*options[S, T])
+
+func newOptions[S comparable, T any](opts []Option[S, T]) *options[S, T] {
+	o := &options[S, T]{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMaxEntries bounds the number of entries held by the cache.
+// When a Put would exceed the bound, the least recently used entry is evicted.
+// A value of zero or less means unbounded.
+func WithMaxEntries[S comparable, T any](n int) Option[S, T] {
+	return func(o *options[S, T]) {
+		o.maxEntries = n
+	}
+}
+
+// WithMaxCost bounds the summed cost of the entries held by the cache,
+// where cost reports the weight of a single value (e.g. its size in bytes).
+// When a Put would exceed the budget, least recently used entries are evicted
+// until the total fits. A value costing more than the budget is not retained,
+// and evicts the entry it replaces but no other.
+func WithMaxCost[S comparable, T any](budget int64, cost func(T) int64) Option[S, T] {
+	return func(o *options[S, T]) {
+		o.maxCost = budget
+		o.cost = cost
+	}
+}
+
+// WithRemovalListener registers listener to be called whenever an entry leaves the cache.
+func WithRemovalListener[S comparable, T any](listener RemovalListener[S, T]) Option[S, T] {
+	return func(o *options[S, T]) {
+		o.listeners = append(o.listeners, listener)
+	}
+}
+
+// WithSlidingExpiration makes every hit push the expiration of an entry forward
+// by the TTL it was put with, so that entries expire once they are no longer read.
+func WithSlidingExpiration[S comparable, T any]() Option[S, T] {
+	return func(o *options[S, T]) {
+		o.sliding = true
+	}
+}
+
+// WithAdmission guards a bounded cache with a W-TinyLFU admission policy.
+// The cache keeps an approximate, periodically aged count of how often each key
+// is accessed. New keys first enter a small window, about 1% of WithMaxEntries
+// and at least one entry, so that they can be read right after they are put.
+// A key pushed out of the window that would overflow the cache is only admitted
+// when it has been accessed more often than the entry it would evict; otherwise
+// it is evicted itself. This keeps one-off scans from flushing the hot set.
+// It has no effect on an unbounded cache.
+func WithAdmission[S comparable, T any]() Option[S, T] {
+	return func(o *options[S, T]) {
+		o.admission = true
+	}
+}
+
+// WithClock makes the cache tell expiration by c instead of the system clock,
+// typically a clock.Manual in tests.
+func WithClock[S comparable, T any](c clock.Clock) Option[S, T] {
+	return func(o *options[S, T]) {
+		o.clock = c
+	}
+}
+
+// WithJanitor makes the cache Flush itself every interval in the background,
+// as Watch would, until it is closed.
+func WithJanitor[S comparable, T any](interval time.Duration) Option[S, T] {
+	return func(o *options[S, T]) {
+		o.janitor = interval
+	}
+}
+
+// WithJitter makes the cache shorten the TTL of every entry by j, so that
+// entries put together, or loaded together by a LoadingCache, expire apart.
+// It applies to entries put with a TTL, not to those put with an expiration.
+func WithJitter[S comparable, T any](j Jitter) Option[S, T] {
+	return func(o *options[S, T]) {
+		o.jitter = j
+	}
+}
+
+// WithHasher makes the cache hash keys with h, which must hash equal keys alike,
+// to pick the shard of a ShardedCache and to count accesses for WithAdmission.
+// Keys other than strings, booleans and numbers need one to be hashed without
+// allocating and to be hashed alike whenever they are equal.
+func WithHasher[S comparable, T any](h func(S) uint64) Option[S, T] {
+	return func(o *options[S, T]) {
+		o.hasher = h
+	}
+}
+
+// WithRefreshAfter makes a LoadingCache reload entries older than d in the background.
+// Until the reload completes, Get keeps returning the cached value.
+func WithRefreshAfter[S comparable, T any](d time.Duration) Option[S, T] {
+	return func(o *options[S, T]) {
+		o.refreshAfter = d
+	}
+}
+
+// WithNegativeTTL makes a LoadingCache remember keys its loader reported as
+// ErrNotFound for ttl, so that repeated lookups of missing keys skip the loader.
+// The keys remembered are bounded by WithMaxEntries and flushed by WithJanitor
+// like the entries of the cache.
+func WithNegativeTTL[S comparable, T any](ttl time.Duration) Option[S, T] {
+	return func(o *options[S, T]) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithBatchLoader makes LoadingCache.GetAll fetch all of its misses with a single
+// call to loader. Keys absent from the returned map are treated as not found.
+func WithBatchLoader[S comparable, T any](loader function.BatchLoader[S, T]) Option[S, T] {
+	return func(o *options[S, T]) {
+		o.batchLoader = loader
+	}
+}
@@ -0,0 +1,57 @@
+package cache
+
+import "time"
+
+// RemovalReason describes why an entry left the cache.
+type RemovalReason int
+
+const (
+	// Expired means Get found the entry past its expiration.
+	Expired RemovalReason = iota
+	// Flushed means Flush removed the entry past its expiration.
+	Flushed
+	// Deleted means the entry was removed by Delete.
+	Deleted
+	// Cleared means the entry was removed by Clear.
+	Cleared
+	// Evicted means the entry was removed to keep the cache within its bounds.
+	Evicted
+	// Replaced means the entry was overwritten by a Put for the same key.
+	Replaced
+	// Invalidated means another replica of the cache wrote or deleted the key.
+	Invalidated
+)
+
+func (r RemovalReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Flushed:
+		return "flushed"
+	case Deleted:
+		return "deleted"
+	case Cleared:
+		return "cleared"
+	case Evicted:
+		return "evicted"
+	case Replaced:
+		return "replaced"
+	case Invalidated:
+		return "invalidated"
+	default:
+		return "unknown"
+	}
+}
+
+// RemovalListener is called with the key and value of an entry that left the cache.
+type RemovalListener[S comparable, T any] func(key S, value T, reason RemovalReason)
+
+type removal[S comparable, T any] struct {
+	key        S
+	value      T
+	expiration time.Time
+	reason     RemovalReason
+}
+
+// spillListener is a RemovalListener that is also told when the entry would have expired.
+type spillListener[S comparable, T any] func(key S, value T, expiration time.Time, reason RemovalReason)
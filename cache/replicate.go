@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"github.com/anaregdesign/papaya/concurrent/pubsub"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// Invalidation is published by a replicated cache whenever it writes or deletes a key.
+type Invalidation[S comparable] struct {
+	Source string
+	Key    S
+}
+
+// replication publishes the invalidations of a cache from its own goroutine,
+// so that a slow subscriber never blocks writers to the cache.
+type replication[S comparable] struct {
+	source string
+	topic  *pubsub.Topic[Invalidation[S]]
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	queue  []S
+	signal chan struct{}
+}
+
+func (r *replication[S]) push(keys []S) {
+	r.mu.Lock()
+	r.queue = append(r.queue, keys...)
+	r.mu.Unlock()
+
+	select {
+	case r.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (r *replication[S]) drain() []S {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	queue := r.queue
+	r.queue = nil
+	return queue
+}
+
+// run publishes the queued invalidations until ctx is done.
+func (r *replication[S]) run(ctx context.Context) {
+	for {
+		select {
+		case <-r.signal:
+		case <-ctx.Done():
+			return
+		}
+		for _, key := range r.drain() {
+			r.topic.Publish(Invalidation[S]{Source: r.source, Key: key})
+		}
+	}
+}
+
+// Replicate ties the cache to topic until ctx is done. While tied, every write
+// or deletion of a key publishes an Invalidation, and the Invalidations that
+// other caches publish on topic delete the key from this cache, with the reason
+// Invalidated. The cache subscribes to topic under name, which must be unique
+// among the caches sharing the topic, and applies the Invalidations it receives
+// one at a time, in the order they arrive. Calling Replicate again stops the
+// previous replication of the cache before tying it to the new topic.
+func (c *Cache[S, T]) Replicate(ctx context.Context, topic *pubsub.Topic[Invalidation[S]], name string) {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &replication[S]{
+		source: uuid.New().String(),
+		topic:  topic,
+		cancel: cancel,
+		signal: make(chan struct{}, 1),
+	}
+	sub := topic.NewSubscription(name, 1, time.Minute, time.Minute)
+	if previous := c.replication.Swap(r); previous != nil {
+		previous.cancel()
+	}
+
+	go sub.Subscribe(ctx, func(m *pubsub.Message[Invalidation[S]]) {
+		defer m.Ack()
+		// A stopped replication may still be handed a message it had queued.
+		if c.replication.Load() != r {
+			return
+		}
+		if m.Body().Source != r.source {
+			c.evictInvalidated(m.Body().Key)
+		}
+	})
+	go r.run(ctx)
+	go func() {
+		<-ctx.Done()
+		c.replication.CompareAndSwap(r, nil)
+	}()
+}
+
+func (c *Cache[S, T]) evictInvalidated(key S) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.remove(key, Invalidated)
+}
+
+// invalidate queues an Invalidation for key if the cache is replicated.
+// The caller must hold c.mu.
+func (c *Cache[S, T]) invalidate(key S) {
+	if c.replication.Load() != nil {
+		c.invalidations = append(c.invalidations, key)
+	}
+}
+
+// publish hands keys to the replication, if any, to be published in the background.
+func (c *Cache[S, T]) publish(keys []S) {
+	if len(keys) == 0 {
+		return
+	}
+	if r := c.replication.Load(); r != nil {
+		r.push(keys)
+	}
+}
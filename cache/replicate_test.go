@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"github.com/anaregdesign/papaya/concurrent/pubsub"
+	"testing"
+	"time"
+)
+
+func TestCache_Replicate(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name      string
+		run       func(local, remote *Cache[S, T])
+		wantLocal bool
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "remote put evicts the key",
+			run: func(local, remote *Cache[string, int]) {
+				remote.Put("a", 2)
+			},
+			wantLocal: false,
+		},
+		{
+			name: "remote delete evicts the key",
+			run: func(local, remote *Cache[string, int]) {
+				remote.Delete("a")
+			},
+			wantLocal: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			topic := pubsub.NewTopic[Invalidation[string]]("invalidations")
+			local := NewCache[string, int](time.Minute)
+			remote := NewCache[string, int](time.Minute)
+			local.Replicate(ctx, topic, "local")
+			remote.Replicate(ctx, topic, "remote")
+
+			reasons := make(chan RemovalReason, 16)
+			local.OnRemoval(func(key string, value int, reason RemovalReason) {
+				reasons <- reason
+			})
+			local.PutWithTTL("a", 1, time.Minute)
+			tt.run(local, remote)
+
+			deadline := time.Now().Add(time.Second)
+			for local.Has("a") != tt.wantLocal && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if got := local.Has("a"); got != tt.wantLocal {
+				t.Errorf("Has() = %v, want %v", got, tt.wantLocal)
+			}
+			if !tt.wantLocal {
+				select {
+				case got := <-reasons:
+					if got != Invalidated {
+						t.Errorf("reason = %v, want %v", got, Invalidated)
+					}
+				case <-time.After(time.Second):
+					t.Errorf("no removal reported")
+				}
+			}
+		})
+	}
+}
+
+func TestCache_Replicate_own(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	topic := pubsub.NewTopic[Invalidation[string]]("invalidations")
+	local := NewCache[string, int](time.Minute)
+	local.Replicate(ctx, topic, "local")
+	local.Put("a", 1)
+	local.Put("sentinel", 1)
+
+	// Invalidations are applied in order, so once the sentinel from another
+	// source is evicted, the cache has also seen its own invalidation of a.
+	topic.Publish(Invalidation[string]{Source: local.replication.Load().source, Key: "a"})
+	topic.Publish(Invalidation[string]{Source: "remote", Key: "sentinel"})
+
+	deadline := time.Now().Add(time.Second)
+	for local.Has("sentinel") {
+		if time.Now().After(deadline) {
+			t.Fatalf("sentinel was not invalidated")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !local.Has("a") {
+		t.Errorf("Has(a) = false, want true")
+	}
+}
+
+func TestCache_Replicate_again(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := pubsub.NewTopic[Invalidation[string]]("first")
+	second := pubsub.NewTopic[Invalidation[string]]("second")
+	local := NewCache[string, int](time.Minute)
+	local.Replicate(ctx, first, "local")
+	local.Replicate(ctx, second, "local")
+	local.Put("a", 1)
+	local.Put("sentinel", 1)
+
+	first.Publish(Invalidation[string]{Source: "remote", Key: "a"})
+	second.Publish(Invalidation[string]{Source: "remote", Key: "sentinel"})
+
+	deadline := time.Now().Add(time.Second)
+	for local.Has("sentinel") {
+		if time.Now().After(deadline) {
+			t.Fatalf("sentinel was not invalidated")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if !local.Has("a") {
+		t.Errorf("Has(a) = false, want true: the first replication is still running")
+	}
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// ShardedCache spreads its entries over independent Cache shards by the hash
+// of their keys, so that operations on different shards never contend for the same lock.
+type ShardedCache[S comparable, T any] struct {
+	shards []*Cache[S, T]
+	hasher func(S) uint64
+}
+
+// NewShardedCache returns a cache split into the given number of shards.
+// Options apply to every shard, so bounds such as WithMaxEntries hold per shard.
+func NewShardedCache[S comparable, T any](shards int, defaultTTL time.Duration, opts ...Option[S, T]) *ShardedCache[S, T] {
+	if shards < 1 {
+		shards = 1
+	}
+	c := &ShardedCache[S, T]{
+		shards: make([]*Cache[S, T], shards),
+		hasher: newOptions(opts).hasher,
+	}
+	if c.hasher == nil {
+		c.hasher = hash[S]
+	}
+	for i := range c.shards {
+		c.shards[i] = NewCache[S, T](defaultTTL, opts...)
+	}
+	return c
+}
+
+func (c *ShardedCache[S, T]) shard(key S) *Cache[S, T] {
+	return c.shards[c.hasher(key)%uint64(len(c.shards))]
+}
+
+func (c *ShardedCache[S, T]) Get(key S) (T, bool) {
+	return c.shard(key).Get(key)
+}
+
+func (c *ShardedCache[S, T]) PutWithExpiration(key S, value T, expiration time.Time) {
+	c.shard(key).PutWithExpiration(key, value, expiration)
+}
+
+func (c *ShardedCache[S, T]) PutWithTTL(key S, value T, ttl time.Duration) {
+	c.shard(key).PutWithTTL(key, value, ttl)
+}
+
+func (c *ShardedCache[S, T]) Put(key S, value T) {
+	c.shard(key).Put(key, value)
+}
+
+func (c *ShardedCache[S, T]) Delete(key S) {
+	c.shard(key).Delete(key)
+}
+
+func (c *ShardedCache[S, T]) Has(key S) bool {
+	return c.shard(key).Has(key)
+}
+
+func (c *ShardedCache[S, T]) Clear() {
+	for _, s := range c.shards {
+		s.Clear()
+	}
+}
+
+func (c *ShardedCache[S, T]) Count() int {
+	var count int
+	for _, s := range c.shards {
+		count += s.Count()
+	}
+	return count
+}
+
+func (c *ShardedCache[S, T]) Flush() {
+	for _, s := range c.shards {
+		s.Flush()
+	}
+}
+
+// OnRemoval registers listener on every shard.
+func (c *ShardedCache[S, T]) OnRemoval(listener RemovalListener[S, T]) {
+	for _, s := range c.shards {
+		s.OnRemoval(listener)
+	}
+}
+
+// Stats returns the sum of the counters of all shards.
+func (c *ShardedCache[S, T]) Stats() Stats {
+	var total Stats
+	for _, s := range c.shards {
+		st := s.Stats()
+		total.Hits += st.Hits
+		total.Misses += st.Misses
+		total.Expirations += st.Expirations
+		total.Flushes += st.Flushes
+		total.Evictions += st.Evictions
+		total.Loads += st.Loads
+		total.LoadFailures += st.LoadFailures
+		total.LoadTime += st.LoadTime
+	}
+	return total
+}
+
+// Close stops the janitors of the shards, as Cache.Close does.
+func (c *ShardedCache[S, T]) Close() error {
+	for _, shard := range c.shards {
+		shard.Close()
+	}
+	return nil
+}
+
+func (c *ShardedCache[S, T]) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.Flush()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShardedCache(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name   string
+		c      *ShardedCache[S, T]
+		keys   []S
+		delete []S
+		want   int
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "entries are spread and found again",
+			c:    NewShardedCache[string, int](8, time.Minute),
+			keys: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"},
+			want: 10,
+		},
+		{
+			name:   "delete reaches the owning shard",
+			c:      NewShardedCache[string, int](8, time.Minute),
+			keys:   []string{"a", "b", "c"},
+			delete: []string{"b"},
+			want:   2,
+		},
+		{
+			name: "single shard",
+			c:    NewShardedCache[string, int](0, time.Minute),
+			keys: []string{"a", "b"},
+			want: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, key := range tt.keys {
+				tt.c.Put(key, i)
+			}
+			for _, key := range tt.delete {
+				tt.c.Delete(key)
+			}
+			if got := tt.c.Count(); got != tt.want {
+				t.Errorf("Count() = %v, want %v", got, tt.want)
+			}
+			for _, key := range tt.delete {
+				if tt.c.Has(key) {
+					t.Errorf("Has(%v) = true, want false", key)
+				}
+			}
+			tt.c.Clear()
+			if got := tt.c.Count(); got != 0 {
+				t.Errorf("Count() = %v, want %v", got, 0)
+			}
+		})
+	}
+}
+
+type benchCache interface {
+	Get(key string) (int, bool)
+	Put(key string, value int)
+}
+
+func benchmarkParallel(b *testing.B, c benchCache, writes int) {
+	keys := make([]string, 1<<12)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+		c.Put(keys[i], i)
+	}
+	var next atomic.Uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(next.Add(1)) * 7919
+		for pb.Next() {
+			key := keys[i&(len(keys)-1)]
+			if i%100 < writes {
+				c.Put(key, i)
+			} else {
+				c.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkCache_parallelRead(b *testing.B) {
+	benchmarkParallel(b, NewCache[string, int](time.Minute), 10)
+}
+
+func BenchmarkShardedCache_parallelRead(b *testing.B) {
+	benchmarkParallel(b, NewShardedCache[string, int](32, time.Minute), 10)
+}
+
+func BenchmarkCache_parallelWrite(b *testing.B) {
+	benchmarkParallel(b, NewCache[string, int](time.Minute), 90)
+}
+
+func BenchmarkShardedCache_parallelWrite(b *testing.B) {
+	benchmarkParallel(b, NewShardedCache[string, int](32, time.Minute), 90)
+}
+
+func TestShardedCache_keys(t *testing.T) {
+	negativeZero := math.Copysign(0, -1)
+	floats := NewShardedCache[float64, int](64, time.Minute)
+	floats.Put(negativeZero, 1)
+	if _, ok := floats.Get(0); !ok {
+		t.Errorf("Get(0) after Put(-0) = false, want true")
+	}
+
+	type point struct{ x, y int }
+	var hashed atomic.Int32
+	points := NewShardedCache[point, int](8, time.Minute, WithHasher[point, int](func(p point) uint64 {
+		hashed.Add(1)
+		return hash(p.x) ^ mix(hash(p.y))
+	}))
+	points.Put(point{1, 2}, 1)
+	if _, ok := points.Get(point{1, 2}); !ok {
+		t.Errorf("Get(point) = false, want true")
+	}
+	if got := hashed.Load(); got != 2 {
+		t.Errorf("hasher calls = %v, want %v", got, 2)
+	}
+}
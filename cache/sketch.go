@@ -0,0 +1,70 @@
+package cache
+
+// sketch is a count-min sketch estimating how often keys were accessed.
+// Counters saturate at 15 and are all halved once the number of increments
+// reaches ten times the width, so that the estimates favour recent history.
+type sketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	additions int
+	period    int
+}
+
+func newSketch(capacity int) *sketch {
+	width := 256
+	for width < capacity {
+		width <<= 1
+	}
+	s := &sketch{mask: uint64(width - 1), period: 10 * width}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// seeds make the rows of a sketch hash independently of one another, so that
+// two keys sharing a counter in one row rarely share it in the others.
+var seeds = [4]uint64{0x9e3779b97f4a7c15, 0xc2b2ae3d27d4eb4f, 0x165667b19e3779f9, 0x27d4eb2f165667c5}
+
+// index returns the counter of h in row i.
+func (s *sketch) index(h uint64, i int) uint64 {
+	return mix(h^seeds[i]) & s.mask
+}
+
+// increment records an access to the key hashed to h.
+func (s *sketch) increment(h uint64) {
+	added := false
+	for i := range s.rows {
+		if j := s.index(h, i); s.rows[i][j] < 15 {
+			s.rows[i][j]++
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+	if s.additions++; s.additions >= s.period {
+		s.age()
+	}
+}
+
+// estimate returns the approximate number of accesses to the key hashed to h.
+func (s *sketch) estimate(h uint64) uint8 {
+	min := uint8(15)
+	for i := range s.rows {
+		if c := s.rows[i][s.index(h, i)]; c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// age halves every counter.
+func (s *sketch) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
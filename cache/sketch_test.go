@@ -0,0 +1,61 @@
+package cache
+
+import "testing"
+
+func Test_sketch(t *testing.T) {
+	s := newSketch(64)
+	for i := 0; i < 20; i++ {
+		s.increment(hash("hot"))
+	}
+	s.increment(hash("cold"))
+
+	if got := s.estimate(hash("hot")); got != 15 {
+		t.Errorf("estimate(hot) = %v, want %v", got, 15)
+	}
+	if got := s.estimate(hash("cold")); got != 1 {
+		t.Errorf("estimate(cold) = %v, want %v", got, 1)
+	}
+	if got := s.estimate(hash("missing")); got > 1 {
+		t.Errorf("estimate(missing) = %v, want at most %v", got, 1)
+	}
+
+	s.age()
+	if got := s.estimate(hash("hot")); got != 7 {
+		t.Errorf("estimate(hot) after age = %v, want %v", got, 7)
+	}
+	if got := s.estimate(hash("cold")); got != 0 {
+		t.Errorf("estimate(cold) after age = %v, want %v", got, 0)
+	}
+}
+
+func Test_sketch_period(t *testing.T) {
+	s := newSketch(64)
+	for i := 0; i < s.period; i++ {
+		s.increment(uint64(i))
+	}
+	if s.additions >= s.period {
+		t.Errorf("additions = %v, want less than %v after aging", s.additions, s.period)
+	}
+}
+
+func Test_sketch_index(t *testing.T) {
+	s := newSketch(0)
+	// Keys sharing their counters with key 0 in the first two rows should still
+	// rarely share them in the other two, were the rows hashed independently.
+	shared, all := 0, 0
+	for h := uint64(1); h < 1<<22; h++ {
+		if s.index(h, 0) != s.index(0, 0) || s.index(h, 1) != s.index(0, 1) {
+			continue
+		}
+		shared++
+		if s.index(h, 2) == s.index(0, 2) && s.index(h, 3) == s.index(0, 3) {
+			all++
+		}
+	}
+	if shared == 0 {
+		t.Fatalf("no key shares the first two counters of key 0")
+	}
+	if all > shared/10 {
+		t.Errorf("%v of %v keys sharing two counters with key 0 share all four", all, shared)
+	}
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Codec encodes and decodes the entries of a cache snapshot.
+type Codec interface {
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Encode(w io.Writer, v any) error {
+	return gob.NewEncoder(w).Encode(v)
+}
+
+func (gobCodec) Decode(r io.Reader, v any) error {
+	return gob.NewDecoder(r).Decode(v)
+}
+
+var (
+	// JSON encodes snapshots with encoding/json.
+	JSON Codec = jsonCodec{}
+	// Gob encodes snapshots with encoding/gob.
+	Gob Codec = gobCodec{}
+)
+
+// Entry is a cached key and value with the absolute time it expires at.
+type Entry[S comparable, T any] struct {
+	Key        S         `json:"key"`
+	Value      T         `json:"value"`
+	Expiration time.Time `json:"expiration"`
+}
+
+// Snapshot writes the live entries of the cache and their expiration times to w.
+func (c *Cache[S, T]) Snapshot(w io.Writer, codec Codec) error {
+	return codec.Encode(w, c.Entries())
+}
+
+// Restore puts the entries of a snapshot read from r into the cache,
+// keeping their original expiration times and skipping those that have expired since.
+func (c *Cache[S, T]) Restore(r io.Reader, codec Codec) error {
+	var entries []Entry[S, T]
+	if err := codec.Decode(r, &entries); err != nil {
+		return err
+	}
+
+	now := c.now()
+	for _, e := range entries {
+		if !e.Expiration.Before(now) {
+			c.PutWithExpiration(e.Key, e.Value, e.Expiration)
+		}
+	}
+	return nil
+}
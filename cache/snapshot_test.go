@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCache_Snapshot(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name  string
+		codec Codec
+		ttls  map[S]time.Duration
+		wait  time.Duration
+		want  map[S]T
+	}
+	tests := []testCase[string, int]{
+		{
+			name:  "json",
+			codec: JSON,
+			ttls:  map[string]time.Duration{"a": time.Minute, "b": time.Hour, "c": -time.Second},
+			want:  map[string]int{"a": 1, "b": 1},
+		},
+		{
+			name:  "gob",
+			codec: Gob,
+			ttls:  map[string]time.Duration{"a": time.Minute, "b": time.Hour, "c": -time.Second},
+			want:  map[string]int{"a": 1, "b": 1},
+		},
+		{
+			name:  "expired since snapshot",
+			codec: JSON,
+			ttls:  map[string]time.Duration{"a": time.Minute, "b": 10 * time.Millisecond},
+			wait:  20 * time.Millisecond,
+			want:  map[string]int{"a": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := NewCache[string, int](time.Minute)
+			for key, ttl := range tt.ttls {
+				src.PutWithTTL(key, 1, ttl)
+			}
+			var buf bytes.Buffer
+			if err := src.Snapshot(&buf, tt.codec); err != nil {
+				t.Fatalf("Snapshot() error = %v", err)
+			}
+			time.Sleep(tt.wait)
+
+			dst := NewCache[string, int](time.Minute)
+			if err := dst.Restore(&buf, tt.codec); err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			got := make(map[string]int)
+			for key := range tt.ttls {
+				if value, ok := dst.Get(key); ok {
+					got[key] = value
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Restore() = %v, want %v", got, tt.want)
+			}
+			for key := range tt.want {
+				if !dst.cache[key].expiration.Equal(src.cache[key].expiration) {
+					t.Errorf("expiration of %v = %v, want %v", key, dst.cache[key].expiration, src.cache[key].expiration)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a point-in-time snapshot of the counters of a cache.
+type Stats struct {
+	// Hits is the number of lookups that found a live entry.
+	Hits uint64
+	// Misses is the number of lookups that found no live entry.
+	Misses uint64
+	// Expirations is the number of entries found expired and removed by a lookup.
+	Expirations uint64
+	// Flushes is the number of expired entries removed by Flush.
+	Flushes uint64
+	// Evictions is the number of entries removed to keep the cache within its bounds.
+	Evictions uint64
+	// Loads is the number of successful loader calls, plus the number of keys
+	// a batch loader returned a value for.
+	Loads uint64
+	// LoadFailures is the number of loader calls that returned no value, plus
+	// the number of keys a batch loader returned no value for.
+	LoadFailures uint64
+	// LoadTime is the total time spent in the loader.
+	LoadTime time.Duration
+}
+
+// HitRatio returns the fraction of lookups that were hits, or zero without lookups.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+type stats struct {
+	hits         atomic.Uint64
+	misses       atomic.Uint64
+	expirations  atomic.Uint64
+	flushes      atomic.Uint64
+	evictions    atomic.Uint64
+	loads        atomic.Uint64
+	loadFailures atomic.Uint64
+	loadTime     atomic.Int64
+}
+
+func (s *stats) lookup(hit bool) {
+	if hit {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+}
+
+func (s *stats) removal(reason RemovalReason) {
+	switch reason {
+	case Expired:
+		s.expirations.Add(1)
+	case Flushed:
+		s.flushes.Add(1)
+	case Evicted:
+		s.evictions.Add(1)
+	}
+}
+
+func (s *stats) load(elapsed time.Duration, ok bool) {
+	if ok {
+		s.loads.Add(1)
+	} else {
+		s.loadFailures.Add(1)
+	}
+	s.loadTime.Add(int64(elapsed))
+}
+
+// loadBatch records a batch load that found values for loaded keys and none for failed keys.
+func (s *stats) loadBatch(elapsed time.Duration, loaded, failed int) {
+	s.loads.Add(uint64(loaded))
+	s.loadFailures.Add(uint64(failed))
+	s.loadTime.Add(int64(elapsed))
+}
+
+func (s *stats) snapshot() Stats {
+	return Stats{
+		Hits:         s.hits.Load(),
+		Misses:       s.misses.Load(),
+		Expirations:  s.expirations.Load(),
+		Flushes:      s.flushes.Load(),
+		Evictions:    s.evictions.Load(),
+		Loads:        s.loads.Load(),
+		LoadFailures: s.loadFailures.Load(),
+		LoadTime:     time.Duration(s.loadTime.Load()),
+	}
+}
@@ -0,0 +1,257 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Store is the backing store of a WriteCache.
+// Load returns ErrNotFound when the store has no value for key.
+type Store[S comparable, T any] interface {
+	Load(ctx context.Context, key S) (T, error)
+	Save(ctx context.Context, key S, value T) error
+	Delete(ctx context.Context, key S) error
+}
+
+// WriteMode selects when a WriteCache writes to its store.
+type WriteMode int
+
+const (
+	// WriteThrough writes to the store before the cache is updated.
+	WriteThrough WriteMode = iota
+	// WriteBehind updates the cache at once and writes to the store later,
+	// coalescing the writes made to a key since the last batch.
+	WriteBehind
+)
+
+// write is a pending write-behind operation on a key.
+type write[T any] struct {
+	value   T
+	deleted bool
+}
+
+// WriteCache is a Cache whose writes are also applied to a Store,
+// and whose misses are served from the Store.
+type WriteCache[S comparable, T any] struct {
+	ctx   context.Context
+	cache *Cache[S, T]
+	store Store[S, T]
+	mode  WriteMode
+
+	// writing makes write-through writes run one at a time, so that the store
+	// and the cache apply them in the same order.
+	writing sync.Mutex
+
+	mu      sync.Mutex
+	pending map[S]write[T]
+	err     error
+
+	// inflight holds the batch a Sync is writing, so that reads keep seeing it
+	// until the store does. syncing makes Syncs run one at a time, so that
+	// batches reach the store in the order they were taken.
+	inflight map[S]write[T]
+	syncing  sync.Mutex
+
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewWriteThroughCache returns a WriteCache that writes every Put and Delete
+// to store before applying it to the cache.
+func NewWriteThroughCache[S comparable, T any](ctx context.Context, store Store[S, T], defaultTTL time.Duration, opts ...Option[S, T]) *WriteCache[S, T] {
+	c := newWriteCache[S, T](ctx, store, WriteThrough, defaultTTL, opts)
+	close(c.stopped)
+	return c
+}
+
+// NewWriteBehindCache returns a WriteCache that applies every Put and Delete
+// to the cache at once and writes them to store in batches every interval,
+// until ctx is done or the cache is closed.
+func NewWriteBehindCache[S comparable, T any](ctx context.Context, store Store[S, T], defaultTTL time.Duration, interval time.Duration, opts ...Option[S, T]) *WriteCache[S, T] {
+	c := newWriteCache[S, T](ctx, store, WriteBehind, defaultTTL, opts)
+	go c.watch(interval)
+	return c
+}
+
+func newWriteCache[S comparable, T any](ctx context.Context, store Store[S, T], mode WriteMode, defaultTTL time.Duration, opts []Option[S, T]) *WriteCache[S, T] {
+	return &WriteCache[S, T]{
+		ctx:     ctx,
+		cache:   NewCache[S, T](defaultTTL, opts...),
+		store:   store,
+		mode:    mode,
+		pending: make(map[S]write[T]),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Get returns the value for key from the cache, or else from the pending or
+// syncing writes or the store, in which case the value is cached again.
+func (c *WriteCache[S, T]) Get(key S) (T, bool) {
+	value, err := c.GetContext(c.ctx, key)
+	return value, err == nil
+}
+
+// GetContext is Get with the error of the store, or ErrNotFound, on a miss.
+func (c *WriteCache[S, T]) GetContext(ctx context.Context, key S) (T, error) {
+	if value, ok := c.cache.Get(key); ok {
+		return value, nil
+	}
+
+	c.mu.Lock()
+	w, ok := c.pending[key]
+	if !ok {
+		w, ok = c.inflight[key]
+	}
+	c.mu.Unlock()
+	if ok {
+		if w.deleted {
+			var noop T
+			return noop, ErrNotFound
+		}
+		c.cache.Put(key, w.value)
+		return w.value, nil
+	}
+
+	value, err := c.store.Load(ctx, key)
+	if err != nil {
+		var noop T
+		return noop, err
+	}
+	c.cache.Put(key, value)
+	return value, nil
+}
+
+func (c *WriteCache[S, T]) Put(key S, value T) error {
+	return c.PutWithTTL(key, value, c.cache.defaultTTL)
+}
+
+func (c *WriteCache[S, T]) PutWithTTL(key S, value T, ttl time.Duration) error {
+	if c.mode == WriteThrough {
+		c.writing.Lock()
+		defer c.writing.Unlock()
+
+		if err := c.store.Save(c.ctx, key, value); err != nil {
+			return err
+		}
+	} else {
+		c.enqueue(key, write[T]{value: value})
+	}
+	c.cache.PutWithTTL(key, value, ttl)
+	return nil
+}
+
+func (c *WriteCache[S, T]) Delete(key S) error {
+	if c.mode == WriteThrough {
+		c.writing.Lock()
+		defer c.writing.Unlock()
+
+		if err := c.store.Delete(c.ctx, key); err != nil {
+			return err
+		}
+	} else {
+		c.enqueue(key, write[T]{deleted: true})
+	}
+	c.cache.Delete(key)
+	return nil
+}
+
+// Cache returns the underlying cache, e.g. for Stats or Watch.
+// Writes made directly to it bypass the store.
+func (c *WriteCache[S, T]) Cache() *Cache[S, T] {
+	return c.cache
+}
+
+func (c *WriteCache[S, T]) enqueue(key S, w write[T]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[key] = w
+}
+
+// Sync writes the pending write-behind operations to the store. Operations that
+// fail are kept pending, unless overwritten in the meantime, and their errors are
+// returned together with the error of the last failed background batch since the
+// last Sync. Concurrent Syncs run one after the other.
+func (c *WriteCache[S, T]) Sync(ctx context.Context) error {
+	c.mu.Lock()
+	last := c.err
+	c.err = nil
+	c.mu.Unlock()
+
+	return errors.Join(last, c.sync(ctx))
+}
+
+// sync writes the pending operations to the store, and returns the errors of
+// those that failed.
+func (c *WriteCache[S, T]) sync(ctx context.Context) error {
+	c.syncing.Lock()
+	defer c.syncing.Unlock()
+
+	c.mu.Lock()
+	batch := c.pending
+	c.pending = make(map[S]write[T])
+	c.inflight = batch
+	c.mu.Unlock()
+
+	var errs []error
+	failed := make(map[S]write[T])
+	for key, w := range batch {
+		var err error
+		if w.deleted {
+			err = c.store.Delete(ctx, key)
+		} else {
+			err = c.store.Save(ctx, key, w.value)
+		}
+		if err != nil {
+			failed[key] = w
+			errs = append(errs, err)
+		}
+	}
+
+	c.mu.Lock()
+	for key, w := range failed {
+		if _, ok := c.pending[key]; !ok {
+			c.pending[key] = w
+		}
+	}
+	c.inflight = nil
+	c.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// Close stops the background writes and syncs what is still pending.
+// It is safe to call more than once; later calls only sync.
+func (c *WriteCache[S, T]) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+	})
+	<-c.stopped
+	c.cache.Close()
+	return c.Sync(context.Background())
+}
+
+func (c *WriteCache[S, T]) watch(interval time.Duration) {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.sync(c.ctx); err != nil {
+				c.mu.Lock()
+				c.err = err
+				c.mu.Unlock()
+			}
+		case <-c.ctx.Done():
+			return
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type mapStore[S comparable, T any] struct {
+	mu      sync.Mutex
+	values  map[S]T
+	saves   int
+	deletes int
+	err     error
+}
+
+func newMapStore[S comparable, T any]() *mapStore[S, T] {
+	return &mapStore[S, T]{values: make(map[S]T)}
+}
+
+func (s *mapStore[S, T]) Load(ctx context.Context, key S) (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if value, ok := s.values[key]; ok {
+		return value, nil
+	}
+	var noop T
+	return noop, ErrNotFound
+}
+
+func (s *mapStore[S, T]) Save(ctx context.Context, key S, value T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.saves++
+	s.values[key] = value
+	return nil
+}
+
+func (s *mapStore[S, T]) Delete(ctx context.Context, key S) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.deletes++
+	delete(s.values, key)
+	return nil
+}
+
+func TestWriteCache(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name        string
+		mode        WriteMode
+		run         func(c *WriteCache[S, T], s *mapStore[S, T])
+		want        map[S]T
+		wantSaves   int
+		wantDeletes int
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "write through",
+			mode: WriteThrough,
+			run: func(c *WriteCache[string, int], s *mapStore[string, int]) {
+				c.Put("a", 1)
+				c.Put("b", 2)
+				c.Delete("b")
+			},
+			want:        map[string]int{"a": 1},
+			wantSaves:   2,
+			wantDeletes: 1,
+		},
+		{
+			name: "write behind coalesces until sync",
+			mode: WriteBehind,
+			run: func(c *WriteCache[string, int], s *mapStore[string, int]) {
+				c.Put("a", 1)
+				c.Put("a", 2)
+				c.Put("a", 3)
+				c.Put("b", 1)
+				c.Delete("b")
+				if len(s.values) != 0 {
+					t.Errorf("store written before sync: %v", s.values)
+				}
+				c.Sync(context.Background())
+			},
+			want:        map[string]int{"a": 3},
+			wantSaves:   1,
+			wantDeletes: 1,
+		},
+		{
+			name: "close syncs pending writes",
+			mode: WriteBehind,
+			run: func(c *WriteCache[string, int], s *mapStore[string, int]) {
+				c.Put("a", 1)
+				c.Close()
+				c.Close()
+			},
+			want:      map[string]int{"a": 1},
+			wantSaves: 1,
+		},
+		{
+			name: "failed writes stay pending",
+			mode: WriteBehind,
+			run: func(c *WriteCache[string, int], s *mapStore[string, int]) {
+				s.err = errors.New("unavailable")
+				c.Put("a", 1)
+				if err := c.Sync(context.Background()); err == nil {
+					t.Errorf("Sync() error = nil, want error")
+				}
+				s.err = nil
+				if err := c.Sync(context.Background()); err != nil {
+					t.Errorf("Sync() error = %v, want nil", err)
+				}
+			},
+			want:      map[string]int{"a": 1},
+			wantSaves: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMapStore[string, int]()
+			var c *WriteCache[string, int]
+			if tt.mode == WriteThrough {
+				c = NewWriteThroughCache[string, int](context.Background(), s, time.Minute)
+			} else {
+				c = NewWriteBehindCache[string, int](context.Background(), s, time.Minute, time.Hour)
+			}
+			defer c.Close()
+
+			tt.run(c, s)
+			if !reflect.DeepEqual(s.values, tt.want) {
+				t.Errorf("store = %v, want %v", s.values, tt.want)
+			}
+			if s.saves != tt.wantSaves {
+				t.Errorf("saves = %v, want %v", s.saves, tt.wantSaves)
+			}
+			if s.deletes != tt.wantDeletes {
+				t.Errorf("deletes = %v, want %v", s.deletes, tt.wantDeletes)
+			}
+		})
+	}
+}
+
+func TestWriteCache_Get(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name    string
+		stored  map[S]T
+		pending map[S]write[T]
+		key     S
+		want    T
+		wantErr error
+	}
+	tests := []testCase[string, int]{
+		{
+			name:   "miss is loaded from the store",
+			stored: map[string]int{"a": 1},
+			key:    "a",
+			want:   1,
+		},
+		{
+			name:    "pending write wins over the store",
+			stored:  map[string]int{"a": 1},
+			pending: map[string]write[int]{"a": {value: 2}},
+			key:     "a",
+			want:    2,
+		},
+		{
+			name:    "pending delete hides the store",
+			stored:  map[string]int{"a": 1},
+			pending: map[string]write[int]{"a": {deleted: true}},
+			key:     "a",
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "not found",
+			key:     "a",
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMapStore[string, int]()
+			for key, value := range tt.stored {
+				s.values[key] = value
+			}
+			c := NewWriteBehindCache[string, int](context.Background(), s, time.Minute, time.Hour)
+			defer c.Close()
+			for key, w := range tt.pending {
+				c.pending[key] = w
+			}
+
+			got, err := c.GetContext(context.Background(), tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetContext() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// slowStore is a mapStore whose first write blocks until release is closed.
+type slowStore[S comparable, T any] struct {
+	*mapStore[S, T]
+	blocked atomic.Bool
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newSlowStore[S comparable, T any]() *slowStore[S, T] {
+	return &slowStore[S, T]{
+		mapStore: newMapStore[S, T](),
+		entered:  make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+}
+
+func (s *slowStore[S, T]) block() {
+	if s.blocked.CompareAndSwap(false, true) {
+		close(s.entered)
+		<-s.release
+	}
+}
+
+func (s *slowStore[S, T]) Save(ctx context.Context, key S, value T) error {
+	s.block()
+	return s.mapStore.Save(ctx, key, value)
+}
+
+func (s *slowStore[S, T]) Delete(ctx context.Context, key S) error {
+	s.block()
+	return s.mapStore.Delete(ctx, key)
+}
+
+func TestWriteCache_Sync_inflight(t *testing.T) {
+	s := newSlowStore[string, int]()
+	s.values["a"] = 1
+	c := NewWriteBehindCache[string, int](context.Background(), s, time.Minute, time.Hour)
+	defer c.Close()
+
+	c.Delete("a")
+	synced := make(chan error)
+	go func() {
+		synced <- c.Sync(context.Background())
+	}()
+	<-s.entered
+
+	if got, err := c.GetContext(context.Background(), "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetContext() during Sync = %v, %v, want %v", got, err, ErrNotFound)
+	}
+	close(s.release)
+	if err := <-synced; err != nil {
+		t.Fatalf("Sync() = %v", err)
+	}
+	if got, err := c.GetContext(context.Background(), "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetContext() after Sync = %v, %v, want %v", got, err, ErrNotFound)
+	}
+}
+
+func TestWriteCache_Sync_ordered(t *testing.T) {
+	s := newSlowStore[string, int]()
+	c := NewWriteBehindCache[string, int](context.Background(), s, time.Minute, time.Hour)
+	defer c.Close()
+
+	c.Put("a", 1)
+	first := make(chan error)
+	go func() {
+		first <- c.Sync(context.Background())
+	}()
+	<-s.entered
+
+	c.Put("a", 2)
+	second := make(chan error)
+	go func() {
+		second <- c.Sync(context.Background())
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(s.release)
+	if err := <-first; err != nil {
+		t.Fatalf("Sync() = %v", err)
+	}
+	if err := <-second; err != nil {
+		t.Fatalf("Sync() = %v", err)
+	}
+
+	if got, _ := s.Load(context.Background(), "a"); got != 2 {
+		t.Errorf("stored value = %v, want %v", got, 2)
+	}
+}
+
+// savedStore is a slowStore whose first Save blocks after the value is saved.
+type savedStore[S comparable, T any] struct {
+	*slowStore[S, T]
+}
+
+func (s savedStore[S, T]) Save(ctx context.Context, key S, value T) error {
+	err := s.mapStore.Save(ctx, key, value)
+	s.block()
+	return err
+}
+
+func TestWriteCache_Put_concurrent(t *testing.T) {
+	s := savedStore[string, int]{newSlowStore[string, int]()}
+	c := NewWriteThroughCache[string, int](context.Background(), s, time.Minute)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		c.Put("a", 1)
+	}()
+	<-s.entered
+	go func() {
+		defer wg.Done()
+		c.Put("a", 2)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(s.release)
+	wg.Wait()
+
+	stored, _ := s.Load(context.Background(), "a")
+	if got, _ := c.Get("a"); got != stored {
+		t.Errorf("Get() = %v, want the stored value %v", got, stored)
+	}
+}
+
+func TestWriteCache_Sync_errors(t *testing.T) {
+	s := newMapStore[string, int]()
+	unavailable := errors.New("unavailable")
+	s.err = unavailable
+	c := NewWriteBehindCache[string, int](context.Background(), s, time.Minute, time.Millisecond)
+	defer c.Close()
+
+	c.Put("a", 1)
+	time.Sleep(50 * time.Millisecond)
+	err := c.Sync(context.Background())
+	if got := countErrors(err, unavailable); got < 1 || got > 2 {
+		t.Errorf("Sync() error holds %v store errors, want 1 or 2: %v", got, err)
+	}
+	s.mu.Lock()
+	s.err = nil
+	s.mu.Unlock()
+}
+
+// countErrors returns how many times target occurs in the tree of err.
+func countErrors(err error, target error) int {
+	if err == target {
+		return 1
+	}
+	n := 0
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			n += countErrors(err, target)
+		}
+	case interface{ Unwrap() error }:
+		n += countErrors(e.Unwrap(), target)
+	}
+	return n
+}
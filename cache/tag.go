@@ -0,0 +1,56 @@
+package cache
+
+import "time"
+
+// PutWithTags puts value with the default TTL and attaches tags to the entry,
+// so that it can be removed together with other entries by InvalidateTag.
+// Putting a key again replaces its tags.
+func (c *Cache[S, T]) PutWithTags(key S, value T, tags ...string) {
+	c.PutWithTTLAndTags(key, value, c.defaultTTL, tags...)
+}
+
+// PutWithTTLAndTags is PutWithTags with an explicit TTL.
+func (c *Cache[S, T]) PutWithTTLAndTags(key S, value T, ttl time.Duration, tags ...string) {
+	ttl = c.jitter.Apply(ttl)
+	c.put(key, value, c.now().Add(ttl), ttl, tags)
+}
+
+// InvalidateTag deletes every entry carrying tag and returns how many there were.
+func (c *Cache[S, T]) InvalidateTag(tag string) int {
+	c.mu.Lock()
+	defer c.unlock()
+
+	keys := c.tagged[tag]
+	n := len(keys)
+	for key := range keys {
+		c.remove(key, Deleted)
+		c.invalidate(key)
+	}
+	return n
+}
+
+// tag indexes key under each of tags. The caller must hold c.mu.
+func (c *Cache[S, T]) tag(key S, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+	if c.tagged == nil {
+		c.tagged = make(map[string]map[S]struct{})
+	}
+	for _, t := range tags {
+		if _, ok := c.tagged[t]; !ok {
+			c.tagged[t] = make(map[S]struct{})
+		}
+		c.tagged[t][key] = struct{}{}
+	}
+}
+
+// untag drops key from the index of each of tags. The caller must hold c.mu.
+func (c *Cache[S, T]) untag(key S, tags []string) {
+	for _, t := range tags {
+		delete(c.tagged[t], key)
+		if len(c.tagged[t]) == 0 {
+			delete(c.tagged, t)
+		}
+	}
+}
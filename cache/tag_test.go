@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCache_InvalidateTag(t *testing.T) {
+	type testCase[S comparable, T any] struct {
+		name       string
+		run        func(c *Cache[S, T])
+		tag        string
+		wantCount  int
+		wantKeys   []S
+		wantTagged int
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "drops every entry with the tag",
+			run: func(c *Cache[string, int]) {
+				c.PutWithTags("a", 1, "tenant-1")
+				c.PutWithTags("b", 2, "tenant-1", "hot")
+				c.PutWithTags("c", 3, "tenant-2")
+				c.Put("d", 4)
+			},
+			tag:        "tenant-1",
+			wantCount:  2,
+			wantKeys:   []string{"c", "d"},
+			wantTagged: 1,
+		},
+		{
+			name: "put replaces tags",
+			run: func(c *Cache[string, int]) {
+				c.PutWithTags("a", 1, "tenant-1")
+				c.Put("a", 2)
+			},
+			tag:        "tenant-1",
+			wantCount:  0,
+			wantKeys:   []string{"a"},
+			wantTagged: 0,
+		},
+		{
+			name: "deleted entries leave the index",
+			run: func(c *Cache[string, int]) {
+				c.PutWithTTLAndTags("a", 1, time.Minute, "tenant-1")
+				c.PutWithTags("b", 2, "tenant-1")
+				c.Delete("a")
+			},
+			tag:        "tenant-1",
+			wantCount:  1,
+			wantKeys:   []string{},
+			wantTagged: 0,
+		},
+		{
+			name: "compute keeps tags",
+			run: func(c *Cache[string, int]) {
+				c.PutWithTags("a", 1, "tenant-1")
+				c.Compute("a", func(old int, ok bool) (int, bool) {
+					return old + 1, true
+				})
+			},
+			tag:        "tenant-1",
+			wantCount:  1,
+			wantKeys:   []string{},
+			wantTagged: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache[string, int](time.Minute)
+			tt.run(c)
+			if got := c.InvalidateTag(tt.tag); got != tt.wantCount {
+				t.Errorf("InvalidateTag() = %v, want %v", got, tt.wantCount)
+			}
+			keys := c.Keys()
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("Keys() = %v, want %v", keys, tt.wantKeys)
+			}
+			if got := len(c.tagged); got != tt.wantTagged {
+				t.Errorf("len(tagged) = %v, want %v", got, tt.wantTagged)
+			}
+		})
+	}
+}
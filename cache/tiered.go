@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/anaregdesign/papaya/model/function"
+	"time"
+)
+
+// TieredCache is a two-tier cache: a Cache as a small, fast L1 in front of a
+// larger Store as L2, typically a FileStore. Entries evicted or expired from L1
+// spill into L2 together with their expiration, and misses on L1 are served
+// from L2 before going to the loader. An entry served from L2 before its
+// expiration moves back into L1. One served after it stays in L2, and is only
+// served for up to the default TTL of L1 past its expiration; it is dropped and
+// loaded again after that.
+type TieredCache[S comparable, T any] struct {
+	ctx    context.Context
+	l1     *Cache[S, T]
+	l2     Store[S, Entry[S, T]]
+	loader function.ContextLoader[S, T]
+	flight group[S, T]
+}
+
+// NewTieredCache returns a TieredCache whose L1 is built from defaultTTL and opts.
+// L2 stores entries rather than values so that they keep their expiration.
+// loader may be nil, in which case misses on both tiers report ErrNotFound.
+// Failures to spill into L2 are dropped; the entry is then loaded again on its next miss.
+func NewTieredCache[S comparable, T any](ctx context.Context, l2 Store[S, Entry[S, T]], loader function.ContextLoader[S, T], defaultTTL time.Duration, opts ...Option[S, T]) *TieredCache[S, T] {
+	c := &TieredCache[S, T]{
+		ctx:    ctx,
+		l2:     l2,
+		loader: loader,
+	}
+	c.l1 = NewCache[S, T](defaultTTL, append(opts, func(o *options[S, T]) {
+		o.spill = c.spill
+	})...)
+	return c
+}
+
+func (c *TieredCache[S, T]) spill(key S, value T, expiration time.Time, reason RemovalReason) {
+	switch reason {
+	case Evicted, Expired, Flushed:
+		if c.lifetime(expiration).After(c.l1.now()) {
+			c.l2.Save(c.ctx, key, Entry[S, T]{Key: key, Value: value, Expiration: expiration})
+		}
+	}
+}
+
+// lifetime returns until when an entry expiring from L1 at expiration is
+// served from L2.
+func (c *TieredCache[S, T]) lifetime(expiration time.Time) time.Time {
+	return expiration.Add(c.l1.defaultTTL)
+}
+
+func (c *TieredCache[S, T]) Get(key S) (T, bool) {
+	value, err := c.GetContext(c.ctx, key)
+	return value, err == nil
+}
+
+// GetContext returns the value for key from L1, else from L2, else from the loader.
+// Entries found live in L2 move back into L1 with their original expiration,
+// expired ones within their lifetime in L2 are served from there, and loaded
+// values are put into L1. Like LoadingCache.GetContext, the lookup runs
+// under the context of the cache, and a caller gives up when its own ctx is done.
+func (c *TieredCache[S, T]) GetContext(ctx context.Context, key S) (T, error) {
+	if value, ok := c.l1.Get(key); ok {
+		return value, nil
+	}
+	return c.flight.do(ctx, key, func() (T, error) {
+		e, err := c.l2.Load(c.ctx, key)
+		if err == nil {
+			now := c.l1.now()
+			switch {
+			case e.Expiration.After(now):
+				c.l2.Delete(c.ctx, key)
+				c.l1.PutWithExpiration(key, e.Value, e.Expiration)
+				return e.Value, nil
+			case c.lifetime(e.Expiration).After(now):
+				return e.Value, nil
+			}
+			c.l2.Delete(c.ctx, key)
+			err = ErrNotFound
+		}
+		if !errors.Is(err, ErrNotFound) || c.loader == nil {
+			var noop T
+			return noop, err
+		}
+
+		start := time.Now()
+		value, err := c.loader(c.ctx, key)
+		c.l1.stats.load(time.Since(start), err == nil)
+		if err != nil {
+			var noop T
+			return noop, err
+		}
+		c.l1.Put(key, value)
+		return value, nil
+	})
+}
+
+func (c *TieredCache[S, T]) Put(key S, value T) {
+	c.PutWithTTL(key, value, c.l1.defaultTTL)
+}
+
+func (c *TieredCache[S, T]) PutWithTTL(key S, value T, ttl time.Duration) {
+	c.l2.Delete(c.ctx, key)
+	c.l1.PutWithTTL(key, value, ttl)
+}
+
+// Delete removes key from both tiers.
+func (c *TieredCache[S, T]) Delete(key S) {
+	c.l1.Delete(key)
+	c.l2.Delete(c.ctx, key)
+}
+
+// Flush removes the expired entries of L1.
+func (c *TieredCache[S, T]) Flush() {
+	c.l1.Flush()
+}
+
+func (c *TieredCache[S, T]) Stats() Stats {
+	return c.l1.Stats()
+}
+
+// Close stops the janitor of L1, as Cache.Close does. It leaves L2 open.
+func (c *TieredCache[S, T]) Close() error {
+	return c.l1.Close()
+}
+
+func (c *TieredCache[S, T]) Watch(ctx context.Context, interval time.Duration) {
+	c.l1.Watch(ctx, interval)
+}
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/anaregdesign/papaya/clock"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTieredCache(t *testing.T) {
+	ctx := context.Background()
+	type testCase[S comparable, T any] struct {
+		name      string
+		run       func(c *TieredCache[S, T])
+		key       S
+		want      T
+		wantErr   error
+		wantLoads int64
+	}
+	tests := []testCase[string, int]{
+		{
+			name: "evicted entry is served from L2",
+			run: func(c *TieredCache[string, int]) {
+				c.Put("a", 1)
+				c.Put("b", 2)
+			},
+			key:  "a",
+			want: 1,
+		},
+		{
+			name: "expired entry is served from L2",
+			run: func(c *TieredCache[string, int]) {
+				c.PutWithTTL("a", 1, -time.Second)
+				c.Flush()
+			},
+			key:  "a",
+			want: 1,
+		},
+		{
+			name: "entry expired past its lifetime in L2 does not spill",
+			run: func(c *TieredCache[string, int]) {
+				c.PutWithTTL("a", 1, -2*time.Minute)
+				c.Flush()
+			},
+			key:       "a",
+			wantErr:   ErrNotFound,
+			wantLoads: 1,
+		},
+		{
+			name: "deleted entry leaves both tiers",
+			run: func(c *TieredCache[string, int]) {
+				c.Put("a", 1)
+				c.Put("b", 2)
+				c.Delete("a")
+			},
+			key:       "a",
+			wantErr:   ErrNotFound,
+			wantLoads: 1,
+		},
+		{
+			name: "overwritten entry hides its spilled value",
+			run: func(c *TieredCache[string, int]) {
+				c.Put("a", 1)
+				c.Put("b", 2)
+				c.Put("a", 3)
+			},
+			key:  "a",
+			want: 3,
+		},
+		{
+			name:      "miss on both tiers goes to the loader",
+			key:       "abc",
+			want:      3,
+			wantLoads: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l2, err := OpenFileStore[string, Entry[string, int]](filepath.Join(t.TempDir(), "segment"), JSON, time.Hour)
+			if err != nil {
+				t.Fatalf("OpenFileStore() error = %v", err)
+			}
+			defer l2.Close()
+
+			var loads atomic.Int64
+			loader := func(ctx context.Context, key string) (int, error) {
+				loads.Add(1)
+				if key == "a" {
+					return 0, ErrNotFound
+				}
+				return len(key), nil
+			}
+			c := NewTieredCache[string, int](ctx, l2, loader, time.Minute, WithMaxEntries[string, int](1))
+			if tt.run != nil {
+				tt.run(c)
+			}
+
+			got, err := c.GetContext(ctx, tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetContext() got = %v, want %v", got, tt.want)
+			}
+			if got := loads.Load(); got != tt.wantLoads {
+				t.Errorf("loads = %v, want %v", got, tt.wantLoads)
+			}
+		})
+	}
+}
+
+func TestTieredCache_expiration(t *testing.T) {
+	ctx := context.Background()
+	l2, err := OpenFileStore[string, Entry[string, int]](filepath.Join(t.TempDir(), "segment"), JSON, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+	defer l2.Close()
+
+	clk := clock.NewManual(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	var loads atomic.Int64
+	loader := func(ctx context.Context, key string) (int, error) {
+		return int(loads.Add(1)), nil
+	}
+	c := NewTieredCache[string, int](ctx, l2, loader, time.Minute, WithMaxEntries[string, int](1), WithClock[string, int](clk))
+
+	type testCase struct {
+		name      string
+		advance   time.Duration
+		key       string
+		want      int
+		wantLoads int64
+	}
+	tests := []testCase{
+		{name: "a is loaded", key: "a", want: 1, wantLoads: 1},
+		{name: "b is loaded and a spills", key: "b", want: 2, wantLoads: 2},
+		{name: "a moves back from L2 and b spills", advance: 30 * time.Second, key: "a", want: 1, wantLoads: 2},
+		{name: "a keeps its expiration and is served from L2", advance: 31 * time.Second, key: "a", want: 1, wantLoads: 2},
+		{name: "a stays in L2 after it expires", key: "a", want: 1, wantLoads: 2},
+		{name: "a is loaded past its lifetime in L2", advance: time.Minute, key: "a", want: 3, wantLoads: 3},
+		{name: "b is loaded past its lifetime in L2", advance: 10 * time.Minute, key: "b", want: 4, wantLoads: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk.Add(tt.advance)
+			got, err := c.GetContext(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("GetContext() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetContext() got = %v, want %v", got, tt.want)
+			}
+			if got := loads.Load(); got != tt.wantLoads {
+				t.Errorf("loads = %v, want %v", got, tt.wantLoads)
+			}
+		})
+	}
+}
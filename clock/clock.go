@@ -0,0 +1,54 @@
+// Package clock abstracts the current time so that expiry can be tested
+// without sleeping.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is the Clock of the system, as reported by time.Now.
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Manual is a Clock that only moves when told to, for tests.
+type Manual struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+// NewManual returns a Manual clock stopped at now.
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+func (m *Manual) Now() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.now
+}
+
+// Add moves the clock forward by d.
+func (m *Manual) Add(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+}
+
+// Set moves the clock to now.
+func (m *Manual) Set(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = now
+}
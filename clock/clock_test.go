@@ -0,0 +1,54 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManual(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	type testCase struct {
+		name string
+		move func(m *Manual)
+		want time.Time
+	}
+	tests := []testCase{
+		{
+			name: "stopped",
+			move: func(m *Manual) {},
+			want: start,
+		},
+		{
+			name: "add",
+			move: func(m *Manual) {
+				m.Add(time.Minute)
+				m.Add(time.Second)
+			},
+			want: start.Add(time.Minute + time.Second),
+		},
+		{
+			name: "set",
+			move: func(m *Manual) {
+				m.Set(start.Add(time.Hour))
+			},
+			want: start.Add(time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManual(start)
+			tt.move(m)
+			if got := m.Now(); !got.Equal(tt.want) {
+				t.Errorf("Now() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReal(t *testing.T) {
+	before := time.Now()
+	got := Real{}.Now()
+	if got.Before(before) || got.After(time.Now()) {
+		t.Errorf("Now() = %v, want between %v and now", got, before)
+	}
+}
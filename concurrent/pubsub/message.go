@@ -37,5 +37,5 @@ func (m *Message[T]) Nack() {
 }
 
 func (m *Message[T]) touch() {
-	m.lastViewedAt = time.Now()
+	m.lastViewedAt = m.subscription.now()
 }
@@ -0,0 +1,26 @@
+package pubsub
+
+import "github.com/anaregdesign/papaya/clock"
+
+type options struct {
+	clock clock.Clock
+}
+
+// Option configures a Topic at construction time.
+type Option func(*options)
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithClock makes the subscriptions of the topic tell the age of messages by c
+// instead of the system clock, typically a clock.Manual in tests.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
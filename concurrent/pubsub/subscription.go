@@ -2,6 +2,7 @@ package pubsub
 
 import (
 	"context"
+	"github.com/anaregdesign/papaya/clock"
 	"github.com/anaregdesign/papaya/model/function"
 	"github.com/google/uuid"
 	"golang.org/x/sync/semaphore"
@@ -14,6 +15,7 @@ type Subscription[T any] struct {
 	wg          sync.WaitGroup
 	name        string
 	topic       *Topic[T]
+	clock       clock.Clock
 	ch          chan string
 	messages    map[string]*Message[T]
 	concurrency int
@@ -38,6 +40,10 @@ func (s *Subscription[T]) Subscribe(ctx context.Context, consumer function.Consu
 		select {
 		case id := <-s.ch:
 			message := s.message(id)
+			if message == nil {
+				// Already acknowledged, e.g. reminded by salvage after delivery.
+				continue
+			}
 
 			s.wg.Add(1)
 			if err := sem.Acquire(ctx, 1); err != nil {
@@ -58,6 +64,14 @@ func (s *Subscription[T]) Subscribe(ctx context.Context, consumer function.Consu
 	}
 }
 
+// now returns the current time by the clock of the topic.
+func (s *Subscription[T]) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
 func (s *Subscription[T]) register() {
 	s.topic.register(s)
 }
@@ -71,7 +85,7 @@ func (s *Subscription[T]) newMessage(body T) *Message[T] {
 		id:           uuid.New().String(),
 		body:         body,
 		subscription: s,
-		createdAt:    time.Now(),
+		createdAt:    s.now(),
 	}
 }
 
@@ -105,12 +119,20 @@ func (s *Subscription[T]) remind(message *Message[T]) {
 }
 
 func (s *Subscription[T]) salvage(interval time.Duration, ttl time.Duration) {
+	s.mu.RLock()
+	messages := make([]*Message[T], 0, len(s.messages))
 	for _, message := range s.messages {
-		if time.Now().Sub(message.createdAt) > ttl {
+		messages = append(messages, message)
+	}
+	s.mu.RUnlock()
+
+	now := s.now()
+	for _, message := range messages {
+		if now.Sub(message.createdAt) > ttl {
 			s.ack(message)
 		}
 
-		if time.Now().Sub(message.lastViewedAt) > interval {
+		if now.Sub(message.lastViewedAt) > interval {
 			s.remind(message)
 		}
 	}
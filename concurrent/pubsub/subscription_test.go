@@ -2,6 +2,7 @@ package pubsub
 
 import (
 	"context"
+	"github.com/anaregdesign/papaya/clock"
 	"github.com/anaregdesign/papaya/model/function"
 	"reflect"
 	"testing"
@@ -335,3 +336,63 @@ func TestSubscription_watch(t *testing.T) {
 		})
 	}
 }
+
+func TestSubscription_salvage_clock(t *testing.T) {
+	type testCase struct {
+		name     string
+		advance  time.Duration
+		wantLeft int
+	}
+	tests := []testCase{
+		{
+			name:     "fresh message is kept",
+			advance:  30 * time.Second,
+			wantLeft: 1,
+		},
+		{
+			name:     "old message is dropped",
+			advance:  2 * time.Minute,
+			wantLeft: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewManual(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+			topic := NewTopic[int]("test", WithClock(clk))
+			sub := topic.NewSubscription("test", 1, 10*time.Second, time.Minute)
+			topic.Publish(1)
+			<-sub.ch
+
+			clk.Add(tt.advance)
+			sub.salvage(sub.interval, sub.ttl)
+			if got := len(sub.messages); got != tt.wantLeft {
+				t.Errorf("len(messages) = %v, want %v", got, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestSubscription_Subscribe_acked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	topic := NewTopic[int]("test")
+	sub := topic.NewSubscription("test", 1, time.Minute, time.Minute)
+	received := make(chan *Message[int], 4)
+	go sub.Subscribe(ctx, func(m *Message[int]) {
+		received <- m
+		if m != nil {
+			m.Ack()
+		}
+	})
+
+	topic.Publish(1)
+	m := <-received
+	// salvage reminds of messages by id, possibly after they were acknowledged.
+	sub.remind(m)
+	topic.Publish(2)
+
+	if m := <-received; m == nil || m.Body() != 2 {
+		t.Errorf("Subscribe() delivered %v, want message 2", m)
+	}
+}
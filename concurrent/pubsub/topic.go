@@ -1,6 +1,7 @@
 package pubsub
 
 import (
+	"github.com/anaregdesign/papaya/clock"
 	"sync"
 	"time"
 )
@@ -8,12 +9,15 @@ import (
 type Topic[T any] struct {
 	mu            sync.RWMutex
 	name          string
+	clock         clock.Clock
 	subscriptions map[string]*Subscription[T]
 }
 
-func NewTopic[T any](name string) *Topic[T] {
+func NewTopic[T any](name string, opts ...Option) *Topic[T] {
+	o := newOptions(opts)
 	return &Topic[T]{
 		name:          name,
+		clock:         o.clock,
 		subscriptions: make(map[string]*Subscription[T]),
 	}
 }
@@ -27,7 +31,14 @@ func (t *Topic[T]) Subscriptions() map[string]*Subscription[T] {
 }
 
 func (t *Topic[T]) Publish(body T) {
+	t.mu.RLock()
+	subscriptions := make([]*Subscription[T], 0, len(t.subscriptions))
 	for _, s := range t.subscriptions {
+		subscriptions = append(subscriptions, s)
+	}
+	t.mu.RUnlock()
+
+	for _, s := range subscriptions {
 		message := s.newMessage(body)
 		s.publish(message)
 	}
@@ -41,6 +52,7 @@ func (t *Topic[T]) NewSubscription(name string, concurrency int, interval time.D
 		t.subscriptions[name] = &Subscription[T]{
 			name:        name,
 			topic:       t,
+			clock:       t.clock,
 			ch:          make(chan string, 65536),
 			messages:    make(map[string]*Message[T]),
 			concurrency: concurrency,
@@ -1,7 +1,9 @@
 package pubsub
 
 import (
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
@@ -210,3 +212,25 @@ func TestTopic_unregister(t1 *testing.T) {
 		})
 	}
 }
+
+func TestTopic_Publish_concurrent(t1 *testing.T) {
+	topic := NewTopic[int]("test")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				topic.Publish(j)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s := topic.NewSubscription(fmt.Sprint(i), 1, time.Minute, time.Minute)
+				s.unregister()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
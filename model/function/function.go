@@ -1,5 +1,7 @@
 package function
 
+import "context"
+
 type Consumer[T any] func(T)
 type Supplier[T any] func() T
 type Predicate[T any] func(T) bool
@@ -7,3 +9,5 @@ type Function[T, R any] func(T) R
 type Operator[T any] func(T, T) T
 
 type Loader[S comparable, T any] func(S) (T, bool)
+type BatchLoader[S comparable, T any] func([]S) map[S]T
+type ContextLoader[S comparable, T any] func(context.Context, S) (T, error)